@@ -55,6 +55,7 @@ type APIServer struct {
 	EnableProfiling            bool
 	EnableWatchCache           bool
 	EtcdPathPrefix             string
+	EtcdPathPrefixOverrides    []string
 	EtcdServerList             []string
 	EtcdServersOverrides       []string
 	EventTTL                   time.Duration
@@ -73,6 +74,7 @@ type APIServer struct {
 	RuntimeConfig              util.ConfigurationMap
 	SSHKeyfile                 string
 	SSHUser                    string
+	SecureBindAddressList      []string
 	ServiceAccountKeyFile      string
 	ServiceAccountLookup       bool
 	ServiceClusterIPRange      net.IPNet // TODO: make this a list
@@ -126,6 +128,10 @@ func (s *APIServer) AddFlags(fs *pflag.FlagSet) {
 		"The IP address on which to listen for the --secure-port port. The "+
 		"associated interface(s) must be reachable by the rest of the cluster, and by CLI/web "+
 		"clients. If blank, all interfaces will be used (0.0.0.0).")
+	fs.StringSliceVar(&s.SecureBindAddressList, "secure-bind-addresses", s.SecureBindAddressList, ""+
+		"Comma-separated list of IP addresses on which to listen for the --secure-port port, "+
+		"one listener per address. Overrides --bind-address for serving. Useful on multi-homed "+
+		"hosts that must not bind all interfaces.")
 	fs.IPVar(&s.AdvertiseAddress, "advertise-address", s.AdvertiseAddress, ""+
 		"The IP address on which to advertise the apiserver to members of the cluster. This "+
 		"address must be reachable by the rest of the cluster. If blank, the --bind-address "+
@@ -173,6 +179,7 @@ func (s *APIServer) AddFlags(fs *pflag.FlagSet) {
 	fs.StringSliceVar(&s.EtcdServerList, "etcd-servers", s.EtcdServerList, "List of etcd servers to watch (http://ip:port), comma separated. Mutually exclusive with -etcd-config")
 	fs.StringSliceVar(&s.EtcdServersOverrides, "etcd-servers-overrides", s.EtcdServersOverrides, "Per-resource etcd servers overrides, comma separated. The individual override format: group/resource#servers, where servers are http://ip:port, semicolon separated.")
 	fs.StringVar(&s.EtcdPathPrefix, "etcd-prefix", s.EtcdPathPrefix, "The prefix for all resource paths in etcd.")
+	fs.StringSliceVar(&s.EtcdPathPrefixOverrides, "etcd-prefix-overrides", s.EtcdPathPrefixOverrides, "Per-group etcd prefix overrides, comma separated. The individual override format: group#prefix. The empty group selects the legacy API group. Groups with their own prefix can be snapshotted and restored independently.")
 	fs.StringSliceVar(&s.CorsAllowedOriginList, "cors-allowed-origins", s.CorsAllowedOriginList, "List of allowed origins for CORS, comma separated.  An allowed origin can be a regular expression to support subdomain matching.  If this list is empty CORS will not be enabled.")
 	fs.BoolVar(&s.AllowPrivileged, "allow-privileged", s.AllowPrivileged, "If true, allow privileged containers.")
 	fs.IPNetVar(&s.ServiceClusterIPRange, "service-cluster-ip-range", s.ServiceClusterIPRange, "A CIDR notation IP range from which to assign service cluster IPs. This must not overlap with any IP ranges assigned to nodes for pods.")
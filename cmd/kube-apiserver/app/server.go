@@ -119,8 +119,25 @@ func generateStorageVersionMap(legacyVersion string, storageVersions string) map
 	return storageVersionMap
 }
 
+// etcdPathPrefixForGroup returns the etcd path prefix a group's storage
+// should write under, preferring a "group#prefix" entry from
+// --etcd-prefix-overrides over the global default.
+func etcdPathPrefixForGroup(prefixOverrides []string, group, defaultPrefix string) string {
+	for _, override := range prefixOverrides {
+		tokens := strings.Split(override, "#")
+		if len(tokens) != 2 {
+			glog.Errorf("invalid value of etcd prefix overrides: %s", override)
+			continue
+		}
+		if tokens[0] == group {
+			return tokens[1]
+		}
+	}
+	return defaultPrefix
+}
+
 // parse the value of --etcd-servers-overrides and update given storageDestinations.
-func updateEtcdOverrides(overrides []string, storageVersions map[string]string, prefix string, storageDestinations *genericapiserver.StorageDestinations, newEtcdFn newEtcdFunc) {
+func updateEtcdOverrides(overrides []string, storageVersions map[string]string, prefixOverrides []string, defaultPrefix string, storageDestinations *genericapiserver.StorageDestinations, newEtcdFn newEtcdFunc) {
 	if len(overrides) == 0 {
 		return
 	}
@@ -149,6 +166,7 @@ func updateEtcdOverrides(overrides []string, storageVersions map[string]string,
 		}
 
 		servers := strings.Split(tokens[1], ";")
+		prefix := etcdPathPrefixForGroup(prefixOverrides, group, defaultPrefix)
 		etcdOverrideStorage, err := newEtcdFn(servers, apigroup.InterfacesFor, storageVersions[apigroup.GroupVersion.Group], prefix)
 		if err != nil {
 			glog.Fatalf("Invalid storage version or misconfigured etcd for %s: %v", tokens[0], err)
@@ -175,6 +193,14 @@ func Run(s *options.APIServer) error {
 	}
 	glog.Infof("Will report %v as public IP address.", s.AdvertiseAddress)
 
+	for _, address := range s.SecureBindAddressList {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			glog.Fatalf("Invalid secure bind address: %s", address)
+		}
+		s.SecureBindAddresses = append(s.SecureBindAddresses, ip)
+	}
+
 	if len(s.EtcdServerList) == 0 {
 		glog.Fatalf("--etcd-servers must be specified")
 	}
@@ -260,11 +286,12 @@ func Run(s *options.APIServer) error {
 	if _, found := storageVersions[legacyV1Group.GroupVersion.Group]; !found {
 		glog.Fatalf("Couldn't find the storage version for group: %q in storageVersions: %v", legacyV1Group.GroupVersion.Group, storageVersions)
 	}
-	etcdStorage, err := newEtcd(s.EtcdServerList, legacyV1Group.InterfacesFor, storageVersions[legacyV1Group.GroupVersion.Group], s.EtcdPathPrefix)
+	legacyPathPrefix := etcdPathPrefixForGroup(s.EtcdPathPrefixOverrides, "", s.EtcdPathPrefix)
+	etcdStorage, err := newEtcd(s.EtcdServerList, legacyV1Group.InterfacesFor, storageVersions[legacyV1Group.GroupVersion.Group], legacyPathPrefix)
 	if err != nil {
 		glog.Fatalf("Invalid storage version or misconfigured etcd: %v", err)
 	}
-	storageDestinations.AddAPIGroup("", etcdStorage)
+	storageDestinations.AddAPIGroupWithPrefix("", etcdStorage, legacyPathPrefix)
 
 	if !apiGroupVersionOverrides["extensions/v1beta1"].Disable {
 		expGroup, err := latest.Group(extensions.GroupName)
@@ -274,14 +301,15 @@ func Run(s *options.APIServer) error {
 		if _, found := storageVersions[expGroup.GroupVersion.Group]; !found {
 			glog.Fatalf("Couldn't find the storage version for group: %q in storageVersions: %v", expGroup.GroupVersion.Group, storageVersions)
 		}
-		expEtcdStorage, err := newEtcd(s.EtcdServerList, expGroup.InterfacesFor, storageVersions[expGroup.GroupVersion.Group], s.EtcdPathPrefix)
+		expPathPrefix := etcdPathPrefixForGroup(s.EtcdPathPrefixOverrides, extensions.GroupName, s.EtcdPathPrefix)
+		expEtcdStorage, err := newEtcd(s.EtcdServerList, expGroup.InterfacesFor, storageVersions[expGroup.GroupVersion.Group], expPathPrefix)
 		if err != nil {
 			glog.Fatalf("Invalid extensions storage version or misconfigured etcd: %v", err)
 		}
-		storageDestinations.AddAPIGroup(extensions.GroupName, expEtcdStorage)
+		storageDestinations.AddAPIGroupWithPrefix(extensions.GroupName, expEtcdStorage, expPathPrefix)
 	}
 
-	updateEtcdOverrides(s.EtcdServersOverrides, storageVersions, s.EtcdPathPrefix, &storageDestinations, newEtcd)
+	updateEtcdOverrides(s.EtcdServersOverrides, storageVersions, s.EtcdPathPrefixOverrides, s.EtcdPathPrefix, &storageDestinations, newEtcd)
 
 	n := s.ServiceClusterIPRange
 
@@ -141,7 +141,7 @@ func TestUpdateEtcdOverrides(t *testing.T) {
 		}
 		storageDestinations := genericapiserver.NewStorageDestinations()
 		override := test.apigroup + "/" + test.resource + "#" + strings.Join(test.servers, ";")
-		updateEtcdOverrides([]string{override}, storageVersions, "", &storageDestinations, newEtcd)
+		updateEtcdOverrides([]string{override}, storageVersions, nil, "", &storageDestinations, newEtcd)
 		apigroup, ok := storageDestinations.APIGroups[test.apigroup]
 		if !ok {
 			t.Errorf("apigroup: %s not created", test.apigroup)
@@ -250,3 +250,20 @@ func TestParseRuntimeConfig(t *testing.T) {
 	}
 
 }
+
+func TestEtcdPathPrefixForGroup(t *testing.T) {
+	overrides := []string{"#/registry-legacy", "extensions#/registry-extensions", "bogus"}
+	testCases := []struct {
+		group    string
+		expected string
+	}{
+		{group: "", expected: "/registry-legacy"},
+		{group: "extensions", expected: "/registry-extensions"},
+		{group: "other", expected: "/registry"},
+	}
+	for _, test := range testCases {
+		if prefix := etcdPathPrefixForGroup(overrides, test.group, "/registry"); prefix != test.expected {
+			t.Errorf("group %q: expected prefix %q, got %q", test.group, test.expected, prefix)
+		}
+	}
+}
@@ -55,6 +55,13 @@ const namespaceKey key = 0
 // userKey is the context key for the request user.
 const userKey key = 1
 
+// certAttributesKey is the context key for attributes extracted from the
+// client certificate.
+const certAttributesKey key = 2
+
+// requestIDKey is the context key for the request correlation ID.
+const requestIDKey key = 3
+
 // NewContext instantiates a base context object for request flows.
 func NewContext() Context {
 	return context.TODO()
@@ -74,6 +81,18 @@ func WithValue(parent Context, key interface{}, val interface{}) Context {
 	return context.WithValue(internalCtx, key, val)
 }
 
+// WithTimeout returns a copy of parent whose Done channel is closed after the
+// given timeout, along with a cancel function releasing its resources. It is
+// used to propagate the HTTP request deadline into the storage tier, so
+// operations already running are aborted when the client's timeout fires.
+func WithTimeout(parent Context, timeout time.Duration) (Context, context.CancelFunc) {
+	internalCtx, ok := parent.(context.Context)
+	if !ok {
+		panic(stderrs.New("Invalid context type"))
+	}
+	return context.WithTimeout(internalCtx, timeout)
+}
+
 // WithNamespace returns a copy of parent in which the namespace value is set
 func WithNamespace(parent Context, namespace string) Context {
 	return WithValue(parent, namespaceKey, namespace)
@@ -114,8 +133,30 @@ func WithUser(parent Context, user user.Info) Context {
 	return WithValue(parent, userKey, user)
 }
 
+// WithCertAttributes returns a copy of parent in which the client certificate attributes value is set
+func WithCertAttributes(parent Context, attributes map[string]string) Context {
+	return WithValue(parent, certAttributesKey, attributes)
+}
+
+// CertAttributesFrom returns the value of the client certificate attributes key on the ctx
+func CertAttributesFrom(ctx Context) (map[string]string, bool) {
+	attributes, ok := ctx.Value(certAttributesKey).(map[string]string)
+	return attributes, ok
+}
+
 // UserFrom returns the value of the user key on the ctx
 func UserFrom(ctx Context) (user.Info, bool) {
 	user, ok := ctx.Value(userKey).(user.Info)
 	return user, ok
 }
+
+// WithRequestID returns a copy of parent in which the request correlation ID is set
+func WithRequestID(parent Context, requestID string) Context {
+	return WithValue(parent, requestIDKey, requestID)
+}
+
+// RequestIDFrom returns the value of the request correlation ID key on the ctx
+func RequestIDFrom(ctx Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDKey).(string)
+	return requestID, ok
+}
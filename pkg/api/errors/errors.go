@@ -177,6 +177,17 @@ func NewGone(message string) error {
 	}}
 }
 
+// NewResourceExpired returns an error indicating that the requested resource
+// version is no longer available, so clients can relist and resume watching.
+func NewResourceExpired(message string) error {
+	return &StatusError{unversioned.Status{
+		Status:  unversioned.StatusFailure,
+		Code:    http.StatusGone,
+		Reason:  unversioned.StatusReasonExpired,
+		Message: message,
+	}}
+}
+
 // NewInvalid returns an error indicating the item is invalid and cannot be processed.
 func NewInvalid(qualifiedKind unversioned.GroupKind, name string, errs field.ErrorList) error {
 	causes := make([]unversioned.StatusCause, 0, len(errs))
@@ -361,6 +372,12 @@ func NewGenericServerResponse(code int, verb string, qualifiedResource unversion
 	}}
 }
 
+// IsResourceExpired determines if the err indicates that the requested
+// resource version is no longer available.
+func IsResourceExpired(err error) bool {
+	return reasonForError(err) == unversioned.StatusReasonExpired
+}
+
 // IsNotFound returns true if the specified error was created by NewNotFound.
 func IsNotFound(err error) bool {
 	return reasonForError(err) == unversioned.StatusReasonNotFound
@@ -106,6 +106,36 @@ func NewRequestContextFilter(mapper RequestContextMapper, handler http.Handler)
 
 }
 
+// RequestContextCleaner is invoked when a request completes, with the context
+// that was associated with the request. It allows features that store
+// per-request state in the context (rate-limit tokens, trace spans) to
+// release those resources deterministically instead of leaking them.
+type RequestContextCleaner interface {
+	CleanupRequestContext(req *http.Request, context Context)
+}
+
+// NewRequestContextFilterWithCleanup behaves like NewRequestContextFilter, but
+// additionally invokes the given cleaner with the request's final context
+// after the handler returns and before the context is dropped.
+func NewRequestContextFilterWithCleanup(mapper RequestContextMapper, cleaner RequestContextCleaner, handler http.Handler) (http.Handler, error) {
+	if mapper, ok := mapper.(*requestContextMap); ok {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if mapper.init(req, NewContext()) {
+				// If we were the ones to successfully initialize, pair with a remove
+				defer func() {
+					if context, ok := mapper.Get(req); ok && cleaner != nil {
+						cleaner.CleanupRequestContext(req, context)
+					}
+					mapper.remove(req)
+				}()
+			}
+			handler.ServeHTTP(w, req)
+		}), nil
+	} else {
+		return handler, errors.New("Unknown RequestContextMapper implementation.")
+	}
+}
+
 // IsEmpty returns true if there are no contexts registered, or an error if it could not be determined. Intended for use by tests.
 func IsEmpty(requestsToContexts RequestContextMapper) (bool, error) {
 	if requestsToContexts, ok := requestsToContexts.(*requestContextMap); ok {
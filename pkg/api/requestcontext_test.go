@@ -0,0 +1,55 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type countingCleaner struct {
+	cleaned int
+}
+
+func (c *countingCleaner) CleanupRequestContext(req *http.Request, context Context) {
+	c.cleaned++
+}
+
+func TestRequestContextFilterWithCleanup(t *testing.T) {
+	mapper := NewRequestContextMapper()
+	cleaner := &countingCleaner{}
+	inner := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, ok := mapper.Get(req); !ok {
+			t.Errorf("expected a context to be associated with the request")
+		}
+	})
+	handler, err := NewRequestContextFilterWithCleanup(mapper, cleaner, inner)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "/api", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if cleaner.cleaned != 1 {
+		t.Errorf("expected cleaner to be invoked once, got %d", cleaner.cleaned)
+	}
+	if empty, err := IsEmpty(mapper); err != nil || !empty {
+		t.Errorf("expected no contexts to remain registered, got empty=%v, err=%v", empty, err)
+	}
+}
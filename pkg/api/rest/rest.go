@@ -20,6 +20,8 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sort"
+	"time"
 
 	"k8s.io/kubernetes/pkg/api"
 	"k8s.io/kubernetes/pkg/api/unversioned"
@@ -270,6 +272,42 @@ type StorageMetadata interface {
 	ProducesMIMETypes(verb string) []string
 }
 
+// PartialResultsLister is an optional interface for Listers able to return
+// the items gathered so far when a LIST cannot complete in time, rather than
+// failing with an error and returning nothing. Clients resume from the
+// returned token.
+type PartialResultsLister interface {
+	// ListPartial behaves like Lister.List, but returns early with the items
+	// collected so far and a resume token once the timeout elapses. An empty
+	// token means the list completed.
+	ListPartial(ctx api.Context, options *api.ListOptions, timeout time.Duration) (runtime.Object, string, error)
+}
+
+// LimitedLister is an optional interface for Listers able to serve a bounded
+// page of a list, returning a continue token when more results remain. It is
+// consulted when a default or client-supplied list limit is in effect, so
+// unbounded LISTs can be paginated by default.
+type LimitedLister interface {
+	// ListLimited behaves like Lister.List, but returns at most limit items
+	// and a resume token when the list was truncated. An empty token means
+	// the list completed.
+	ListLimited(ctx api.Context, options *api.ListOptions, limit int) (runtime.Object, string, error)
+}
+
+// ShortNamesProvider is an optional interface for Storage objects that want
+// to expose short names (e.g. "po" for pods) for their resource in discovery.
+type ShortNamesProvider interface {
+	// ShortNames returns a list of suggested short names for the resource.
+	ShortNames() []string
+}
+
+// CategoriesProvider is an optional interface for Storage objects that want
+// their resource to be grouped into categories (e.g. "all") in discovery.
+type CategoriesProvider interface {
+	// Categories returns a list of the categories the resource belongs to.
+	Categories() []string
+}
+
 // ConnectRequest is an object passed to admission control for Connect operations
 type ConnectRequest struct {
 	// Name is the name of the object on which the connect request was made
@@ -283,3 +321,45 @@ type ConnectRequest struct {
 }
 
 func (obj *ConnectRequest) GetObjectKind() unversioned.ObjectKind { return unversioned.EmptyObjectKind }
+
+// StorageVerbs returns the sorted list of API verbs the given storage
+// supports, derived from the interfaces it implements. It mirrors the
+// interface checks the API installer uses to decide which routes to register.
+func StorageVerbs(storage Storage) []string {
+	verbs := []string{}
+	if _, ok := storage.(Creater); ok {
+		verbs = append(verbs, "create")
+	} else if _, ok := storage.(NamedCreater); ok {
+		verbs = append(verbs, "create")
+	}
+	if _, ok := storage.(Deleter); ok {
+		verbs = append(verbs, "delete")
+	} else if _, ok := storage.(GracefulDeleter); ok {
+		verbs = append(verbs, "delete")
+	}
+	if _, ok := storage.(CollectionDeleter); ok {
+		verbs = append(verbs, "deletecollection")
+	}
+	if _, ok := storage.(Getter); ok {
+		verbs = append(verbs, "get")
+	} else if _, ok := storage.(GetterWithOptions); ok {
+		verbs = append(verbs, "get")
+	}
+	if _, ok := storage.(Lister); ok {
+		verbs = append(verbs, "list")
+	}
+	if _, ok := storage.(Patcher); ok {
+		verbs = append(verbs, "patch")
+	}
+	if _, ok := storage.(Redirector); ok {
+		verbs = append(verbs, "proxy")
+	}
+	if _, ok := storage.(Updater); ok {
+		verbs = append(verbs, "update")
+	}
+	if _, ok := storage.(Watcher); ok {
+		verbs = append(verbs, "watch")
+	}
+	sort.Strings(verbs)
+	return verbs
+}
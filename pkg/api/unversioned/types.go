@@ -339,6 +339,10 @@ type APIResource struct {
 	Name string `json:"name"`
 	// namespaced indicates if a resource is namespaced or not.
 	Namespaced bool `json:"namespaced"`
+	// shortNames is a list of suggested short names of the resource.
+	ShortNames []string `json:"shortNames,omitempty"`
+	// categories is a list of the grouped resources this resource belongs to (e.g. 'all').
+	Categories []string `json:"categories,omitempty"`
 }
 
 // APIResourceList is a list of APIResource, it is used to expose the name of the
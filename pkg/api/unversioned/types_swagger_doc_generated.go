@@ -51,6 +51,8 @@ var map_APIResource = map[string]string{
 	"":           "APIResource specifies the name of a resource and whether it is namespaced.",
 	"name":       "name is the name of the resource.",
 	"namespaced": "namespaced indicates if a resource is namespaced or not.",
+	"shortNames": "shortNames is a list of suggested short names of the resource.",
+	"categories": "categories is a list of the grouped resources this resource belongs to (e.g. 'all').",
 }
 
 func (APIResource) SwaggerDoc() map[string]string {
@@ -442,6 +442,17 @@ func (a *APIInstaller) registerResourceHandlers(path string, storage rest.Storag
 		Resource:    a.group.GroupVersion.WithResource(resource),
 		Subresource: subresource,
 		Kind:        a.group.GroupVersion.WithKind(kind),
+
+		CacheEnabled: a.group.CacheEnabled,
+
+		DefaultListLimit: a.group.DefaultListLimit,
+
+		EmitTimeoutHeader: a.group.EmitTimeoutHeader,
+
+		StreamingDecode: a.group.StreamingDecode,
+	}
+	if override, ok := a.group.ListLimitOverrides[resource]; ok {
+		reqScope.DefaultListLimit = override
 	}
 	for _, action := range actions {
 		reqScope.Namer = action.Namer
@@ -678,6 +689,15 @@ func (a *APIInstaller) registerResourceHandlers(path string, storage rest.Storag
 		}
 		// Note: update GetAttribs() when adding a custom handler.
 	}
+	// Let the storage optionally declare discovery metadata used by clients
+	// like kubectl (e.g. "po" for pods, or membership in the "all" category).
+	if shortNamesProvider, ok := storage.(rest.ShortNamesProvider); ok {
+		apiResource.ShortNames = shortNamesProvider.ShortNames()
+	}
+	if categoriesProvider, ok := storage.(rest.CategoriesProvider); ok {
+		apiResource.Categories = categoriesProvider.Categories()
+	}
+
 	return &apiResource, nil
 }
 
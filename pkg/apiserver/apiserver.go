@@ -28,6 +28,7 @@ import (
 	rt "runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"k8s.io/kubernetes/pkg/admission"
@@ -38,7 +39,6 @@ import (
 	"k8s.io/kubernetes/pkg/api/rest"
 	"k8s.io/kubernetes/pkg/api/unversioned"
 	"k8s.io/kubernetes/pkg/apiserver/metrics"
-	"k8s.io/kubernetes/pkg/healthz"
 	"k8s.io/kubernetes/pkg/runtime"
 	"k8s.io/kubernetes/pkg/util"
 	utilerrors "k8s.io/kubernetes/pkg/util/errors"
@@ -65,6 +65,28 @@ func monitorFilter(action, resource string) restful.FilterFunction {
 	}
 }
 
+// VersionDeprecation describes when an API version is deprecated and where
+// clients can read about migrating off it.
+type VersionDeprecation struct {
+	// Date is when the version was (or will be) deprecated.
+	Date time.Time
+	// DocURL points at migration documentation for the replacement version.
+	DocURL string
+}
+
+// deprecationFilter emits Deprecation and Link response headers (RFC 8594
+// style) on every response served by a deprecated API version, giving clients
+// a machine-readable signal with a pointer to migration guidance.
+func deprecationFilter(deprecation *VersionDeprecation) restful.FilterFunction {
+	return func(req *restful.Request, res *restful.Response, chain *restful.FilterChain) {
+		res.AddHeader("Deprecation", deprecation.Date.UTC().Format(http.TimeFormat))
+		if len(deprecation.DocURL) > 0 {
+			res.AddHeader("Link", fmt.Sprintf("<%s>; rel=\"deprecation\"", deprecation.DocURL))
+		}
+		chain.ProcessFilter(req, res)
+	}
+}
+
 // mux is an object that can register http handlers.
 type Mux interface {
 	Handle(pattern string, handler http.Handler)
@@ -106,6 +128,28 @@ type APIGroupVersion struct {
 	Context api.RequestContextMapper
 
 	MinRequestTimeout time.Duration
+
+	// CacheEnabled indicates that reads may be served from the watch cache,
+	// so handlers should report cache status to clients.
+	CacheEnabled bool
+
+	// Deprecation, if set, marks this version as deprecated; responses carry
+	// Deprecation and Link headers describing the deprecation.
+	Deprecation *VersionDeprecation
+
+	// DefaultListLimit bounds LIST responses when the client does not supply
+	// a limit; zero leaves lists unbounded. ListLimitOverrides adjusts the
+	// default for individual resources.
+	DefaultListLimit   int
+	ListLimitOverrides map[string]int
+
+	// EmitTimeoutHeader reports the effective server-side timeout of watch
+	// requests in an X-Timeout-Seconds response header.
+	EmitTimeoutHeader bool
+
+	// StreamingDecode decodes request bodies directly from the stream when
+	// the codec supports it, instead of buffering the full body first.
+	StreamingDecode bool
 }
 
 type ProxyDialerFunc func(network, addr string) (net.Conn, error)
@@ -124,6 +168,9 @@ const (
 func (g *APIGroupVersion) InstallREST(container *restful.Container) error {
 	installer := g.newInstaller()
 	ws := installer.NewWebService()
+	if g.Deprecation != nil {
+		ws.Filter(deprecationFilter(g.Deprecation))
+	}
 	apiResources, registrationErrors := installer.Install(ws)
 	AddSupportedResourcesWebService(ws, g.GroupVersion, apiResources)
 	container.Add(ws)
@@ -166,10 +213,11 @@ func (g *APIGroupVersion) newInstaller() *APIInstaller {
 }
 
 // TODO: document all handlers
-// InstallSupport registers the APIServer support functions
-func InstallSupport(mux Mux, ws *restful.WebService, enableResettingMetrics bool, checks ...healthz.HealthzChecker) {
-	// TODO: convert healthz and metrics to restful and remove container arg
-	healthz.InstallHandler(mux, checks...)
+// InstallSupport registers the APIServer support functions. The /healthz
+// endpoint is installed by the generic server itself so checks can be
+// registered at runtime.
+func InstallSupport(mux Mux, ws *restful.WebService, enableResettingMetrics bool) {
+	// TODO: convert metrics to restful and remove container arg
 	mux.Handle("/metrics", prometheus.Handler())
 	if enableResettingMetrics {
 		mux.HandleFunc("/resetMetrics", metrics.Reset)
@@ -234,14 +282,28 @@ func serviceErrorHandler(requestResolver *RequestInfoResolver, apiVersions []str
 		}
 	}
 
-	errorJSON(apierrors.NewGenericServerResponse(serviceErr.Code, "", api.Resource(""), "", "", 0, false), codec, response.ResponseWriter)
+	// When the router finds no matching route, name the requested path so the
+	// client gets a proper NotFound status instead of go-restful's default
+	// plain-text response.
+	name := ""
+	if serviceErr.Code == http.StatusNotFound {
+		name = request.Request.URL.Path
+	}
+	errorJSON(apierrors.NewGenericServerResponse(serviceErr.Code, "", api.Resource(""), name, "", 0, false), codec, response.ResponseWriter)
 }
 
 // Adds a service to return the supported api versions at the legacy /api.
 func AddApiWebService(container *restful.Container, apiPrefix string, versions []string) {
+	AddDynamicApiWebService(container, apiPrefix, func() []string { return versions })
+}
+
+// AddDynamicApiWebService is like AddApiWebService, but re-evaluates the
+// supported versions on every request, so versions enabled or disabled at
+// runtime are reflected in discovery.
+func AddDynamicApiWebService(container *restful.Container, apiPrefix string, versions func() []string) {
 	// TODO: InstallREST should register each version automatically
 
-	versionHandler := APIVersionHandler(versions[:]...)
+	versionHandler := DynamicAPIVersionHandler(versions)
 	ws := new(restful.WebService)
 	ws.Path(apiPrefix)
 	ws.Doc("get available API versions")
@@ -270,7 +332,14 @@ func AddApisWebService(container *restful.Container, apiPrefix string, f func()
 // Adds a service to return the supported versions, preferred version, and name
 // of a group. E.g., a such web service will be registered at /apis/extensions.
 func AddGroupWebService(container *restful.Container, path string, group unversioned.APIGroup) {
-	groupHandler := GroupHandler(group)
+	AddDynamicGroupWebService(container, path, func() unversioned.APIGroup { return group })
+}
+
+// AddDynamicGroupWebService is like AddGroupWebService, but re-evaluates the
+// group on every request, so versions enabled or disabled at runtime are
+// reflected in discovery.
+func AddDynamicGroupWebService(container *restful.Container, path string, group func() unversioned.APIGroup) {
+	groupHandler := DynamicGroupHandler(group)
 	ws := new(restful.WebService)
 	ws.Path(path)
 	ws.Doc("get information of a group")
@@ -301,26 +370,65 @@ func handleVersion(req *restful.Request, resp *restful.Response) {
 
 // APIVersionHandler returns a handler which will list the provided versions as available.
 func APIVersionHandler(versions ...string) restful.RouteFunction {
+	return DynamicAPIVersionHandler(func() []string { return versions })
+}
+
+// DynamicAPIVersionHandler returns a handler which lists the versions
+// reported by the provided function as available, re-evaluating it on every
+// request.
+func DynamicAPIVersionHandler(versions func() []string) restful.RouteFunction {
 	return func(req *restful.Request, resp *restful.Response) {
 		// TODO: use restful's Response methods
-		writeJSON(http.StatusOK, api.Codec, &unversioned.APIVersions{Versions: versions}, resp.ResponseWriter, true)
+		writeJSON(http.StatusOK, api.Codec, &unversioned.APIVersions{Versions: versions()}, resp.ResponseWriter, true)
 	}
 }
 
+// discoveryCacheTTL bounds how long the sizing encode performed for the
+// discovery metrics is reused. With many installed groups (e.g. aggregated
+// APIs) encoding the document becomes measurably expensive.
+const discoveryCacheTTL = 5 * time.Second
+
 // RootAPIHandler returns a handler which will list the provided groups and versions as available.
+// The group closure runs on every request, so groups installed at runtime
+// (e.g. third-party APIs) are visible immediately; only the sizing encode
+// performed for the discovery metrics is cached, and it is refreshed early
+// whenever the group set changes.
 func RootAPIHandler(f func() []unversioned.APIGroup) restful.RouteFunction {
+	var lock sync.Mutex
+	var cachedGroups []unversioned.APIGroup
+	var cachedAt time.Time
 	return func(req *restful.Request, resp *restful.Response) {
+		start := time.Now()
+		groups := f()
+		lock.Lock()
+		if cachedGroups == nil || time.Since(cachedAt) > discoveryCacheTTL || !api.Semantic.DeepEqual(groups, cachedGroups) {
+			size := 0
+			if output, err := api.Codec.Encode(&unversioned.APIGroupList{Groups: groups}); err == nil {
+				size = len(output)
+			}
+			metrics.MonitorDiscovery(time.Since(start), size)
+			cachedGroups = groups
+			cachedAt = time.Now()
+		}
+		lock.Unlock()
 		// TODO: use restful's Response methods
-		writeJSON(http.StatusOK, api.Codec, &unversioned.APIGroupList{Groups: f()}, resp.ResponseWriter, true)
+		writeJSON(http.StatusOK, api.Codec, &unversioned.APIGroupList{Groups: groups}, resp.ResponseWriter, true)
 	}
 }
 
 // GroupHandler returns a handler which will return the api.GroupAndVersion of
 // the group.
 func GroupHandler(group unversioned.APIGroup) restful.RouteFunction {
+	return DynamicGroupHandler(func() unversioned.APIGroup { return group })
+}
+
+// DynamicGroupHandler returns a handler which serves the group reported by
+// the provided function, re-evaluating it on every request.
+func DynamicGroupHandler(group func() unversioned.APIGroup) restful.RouteFunction {
 	return func(req *restful.Request, resp *restful.Response) {
 		// TODO: use restful's Response methods
-		writeJSON(http.StatusOK, api.Codec, &group, resp.ResponseWriter, true)
+		g := group()
+		writeJSON(http.StatusOK, api.Codec, &g, resp.ResponseWriter, true)
 	}
 }
 
@@ -2912,7 +2912,7 @@ func TestCORSAllowedOrigins(t *testing.T) {
 
 		handler := CORS(
 			handle(map[string]rest.Storage{}),
-			allowedOriginRegexps, nil, nil, "true",
+			allowedOriginRegexps, nil, nil, "true", 0,
 		)
 		server := httptest.NewServer(handler)
 		// TODO: Uncomment when fix #19254
@@ -0,0 +1,74 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ChaosLatencyConfig describes artificial latency injected into a fraction of
+// requests, for exercising client retry/backoff behavior and downstream
+// timeouts in test environments. Injection is off by default and refuses to
+// activate unless UnsafeAllowChaos is explicitly set, so it cannot be enabled
+// accidentally in production.
+type ChaosLatencyConfig struct {
+	// UnsafeAllowChaos must be explicitly set for any delay to be injected.
+	UnsafeAllowChaos bool
+	// Percent is the percentage of matching requests to delay, in [0, 100].
+	Percent int
+	// MaxDelay bounds the injected delay; each delayed request sleeps a
+	// uniformly random duration in (0, MaxDelay].
+	MaxDelay time.Duration
+	// Verbs restricts injection to the listed HTTP methods. Empty matches all.
+	Verbs []string
+	// PathPrefix restricts injection to request paths with the given prefix.
+	// Empty matches all paths.
+	PathPrefix string
+}
+
+func (c *ChaosLatencyConfig) matches(req *http.Request) bool {
+	if c.PathPrefix != "" && !strings.HasPrefix(req.URL.Path, c.PathPrefix) {
+		return false
+	}
+	if len(c.Verbs) == 0 {
+		return true
+	}
+	for _, verb := range c.Verbs {
+		if strings.EqualFold(verb, req.Method) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithChaosLatency delays the configured fraction of matching requests by a
+// random duration up to config.MaxDelay. When config is nil, not explicitly
+// allowed, or effectively a no-op, the handler is returned unchanged.
+func WithChaosLatency(handler http.Handler, config *ChaosLatencyConfig) http.Handler {
+	if config == nil || !config.UnsafeAllowChaos || config.Percent <= 0 || config.MaxDelay <= 0 {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if config.matches(req) && rand.Intn(100) < config.Percent {
+			time.Sleep(time.Duration(rand.Int63n(int64(config.MaxDelay))) + 1)
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
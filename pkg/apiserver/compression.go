@@ -0,0 +1,83 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"compress/gzip"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// WithCompression gzips responses for clients that advertise gzip support.
+// Long-running requests (watches, proxies, exec) are served uncompressed:
+// gzip buffers output, and a buffered watch stream would delay event delivery
+// indefinitely on a quiet resource. Everything else is compressed with a
+// flush after each write so streamed bodies are never held back.
+func WithCompression(handler http.Handler, longRunningRequestRE *regexp.Regexp) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if longRunningRequestRE.MatchString(req.URL.Path) || isWatchRequest(req) {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		if !acceptsGzip(req) {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		// Compressed length differs from the uncompressed body's.
+		w.Header().Del("Content-Length")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		handler.ServeHTTP(&gzipResponseWriter{ResponseWriter: w, gz: gz}, req)
+	})
+}
+
+func acceptsGzip(req *http.Request) bool {
+	for _, encoding := range strings.Split(req.Header.Get("Accept-Encoding"), ",") {
+		if strings.TrimSpace(strings.SplitN(encoding, ";", 2)[0]) == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter compresses the response body, flushing the compressor
+// after every write so partial output reaches the client immediately.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz *gzip.Writer
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	n, err := w.gz.Write(b)
+	if err != nil {
+		return n, err
+	}
+	return n, w.gz.Flush()
+}
+
+func (w *gzipResponseWriter) Flush() {
+	w.gz.Flush()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *gzipResponseWriter) CloseNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
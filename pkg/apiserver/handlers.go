@@ -18,12 +18,16 @@ package apiserver
 
 import (
 	"bufio"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"regexp"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -31,8 +35,10 @@ import (
 	"github.com/golang/glog"
 	"k8s.io/kubernetes/pkg/api"
 	"k8s.io/kubernetes/pkg/api/errors"
+	"k8s.io/kubernetes/pkg/apiserver/metrics"
 	"k8s.io/kubernetes/pkg/auth/authorizer"
 	"k8s.io/kubernetes/pkg/httplog"
+	"k8s.io/kubernetes/pkg/util"
 	"k8s.io/kubernetes/pkg/util/sets"
 )
 
@@ -74,12 +80,25 @@ func ReadOnly(handler http.Handler) http.Handler {
 
 // MaxInFlight limits the number of in-flight requests to buffer size of the passed in channel.
 func MaxInFlightLimit(c chan bool, longRunningRequestRE *regexp.Regexp, handler http.Handler) http.Handler {
-	if c == nil {
+	return MaxInFlightLimitByClass(c, nil, longRunningRequestRE, handler)
+}
+
+// MaxInFlightLimitByClass limits normal and long-running requests with
+// separate semaphores: requests matching longRunningRequestRE count against
+// longRunning, everything else against normal. A nil channel leaves the
+// corresponding class unlimited, so runaway watch clients can be capped
+// without eating into the budget for short requests.
+func MaxInFlightLimitByClass(normal, longRunning chan bool, longRunningRequestRE *regexp.Regexp, handler http.Handler) http.Handler {
+	if normal == nil && longRunning == nil {
 		return handler
 	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := normal
 		if longRunningRequestRE.MatchString(r.URL.Path) {
-			// Skip tracking long running events.
+			c = longRunning
+		}
+		if c == nil {
+			// This class of request is not tracked.
 			handler.ServeHTTP(w, r)
 			return
 		}
@@ -93,12 +112,353 @@ func MaxInFlightLimit(c chan bool, longRunningRequestRE *regexp.Regexp, handler
 	})
 }
 
+// MaxWatchesPerUser caps how many watch requests a single user may hold open
+// concurrently, returning 429 once the limit is reached. Watches bypass the
+// in-flight limit by design, so without this a single misbehaving client can
+// starve everyone else of connections. The count is keyed on the username in
+// the request context, so the handler must run after authentication; requests
+// without a user share one anonymous bucket. Non-watch requests, including
+// other long-running ones, are unaffected.
+func MaxWatchesPerUser(handler http.Handler, limit int, mapper api.RequestContextMapper) http.Handler {
+	if limit <= 0 {
+		return handler
+	}
+	var lock sync.Mutex
+	active := map[string]int{}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !isWatchRequest(req) {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		username := ""
+		if ctx, ok := mapper.Get(req); ok {
+			if user, ok := api.UserFrom(ctx); ok {
+				username = user.GetName()
+			}
+		}
+		lock.Lock()
+		if active[username] >= limit {
+			lock.Unlock()
+			tooManyRequests(w)
+			return
+		}
+		active[username]++
+		lock.Unlock()
+		defer func() {
+			lock.Lock()
+			active[username]--
+			if active[username] == 0 {
+				delete(active, username)
+			}
+			lock.Unlock()
+		}()
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// WithFairnessHeaders reports how the server classified each request for
+// concurrency-limiting purposes: the priority lane it was assigned
+// (long-running or normal) and the limit bucket that applied (long-running
+// requests are exempt from the in-flight limit). This makes throttling
+// decisions debuggable from the client side instead of opaque.
+func WithFairnessHeaders(handler http.Handler, longRunningRequestRE *regexp.Regexp) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if longRunningRequestRE.MatchString(req.URL.Path) || req.URL.Query().Get("watch") == "true" {
+			w.Header().Set("X-Priority-Lane", "long-running")
+			w.Header().Set("X-Limit-Bucket", "exempt")
+		} else {
+			w.Header().Set("X-Priority-Lane", "normal")
+			w.Header().Set("X-Limit-Bucket", "max-in-flight")
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
 func tooManyRequests(w http.ResponseWriter) {
 	// Return a 429 status indicating "Too Many Requests"
 	w.Header().Set("Retry-After", RetryAfter)
 	http.Error(w, "Too many requests, please try again later.", errors.StatusTooManyRequests)
 }
 
+// WithQueryParamAllowlist rejects requests carrying query parameters outside
+// the configured allowlist for their verb with a 400. Verbs without an entry
+// in the allowlist are not restricted, so the default remains permissive.
+// This catches parameter pollution and shrinks the attack surface for
+// security-conscious deployments.
+func WithQueryParamAllowlist(handler http.Handler, resolver *RequestInfoResolver, allowed map[string]sets.String) http.Handler {
+	if len(allowed) == 0 {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requestInfo, err := resolver.GetRequestInfo(req)
+		if err == nil {
+			if allowedParams, ok := allowed[requestInfo.Verb]; ok {
+				for param := range req.URL.Query() {
+					if !allowedParams.Has(param) {
+						http.Error(w, fmt.Sprintf("Query parameter %q is not permitted for verb %q.", param, requestInfo.Verb), http.StatusBadRequest)
+						return
+					}
+				}
+			}
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// RequireTLSForMutations rejects mutating requests with a 403 directing
+// clients to the secure endpoint, while still serving reads. Intended for
+// the insecure handler, it reduces the blast radius of the unauthenticated
+// port without disabling it entirely.
+func RequireTLSForMutations(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case "GET", "HEAD", "OPTIONS":
+			handler.ServeHTTP(w, req)
+			return
+		}
+		http.Error(w, "Mutating requests are not allowed on the insecure endpoint; use the secure endpoint.", http.StatusForbidden)
+	})
+}
+
+// WithRequestDeadline attaches the request's timeout as a deadline on the
+// context stored in the mapper, so storage operations taking that context are
+// cancelled when the HTTP timeout fires instead of continuing to run against
+// etcd for a client that has already given up. Requests for which timeoutFunc
+// returns zero (long-running requests) are left untouched. Must run inside
+// the request context filter.
+func WithRequestDeadline(handler http.Handler, mapper api.RequestContextMapper, timeoutFunc func(*http.Request) time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if timeout := timeoutFunc(req); timeout > 0 {
+			if ctx, ok := mapper.Get(req); ok {
+				deadlineCtx, cancel := api.WithTimeout(ctx, timeout)
+				defer cancel()
+				if err := mapper.Update(req, deadlineCtx); err != nil {
+					glog.Errorf("Unable to attach deadline to request context: %v", err)
+				}
+			}
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// readOnlyVerbs are the API verbs a read-only listener serves.
+var readOnlyVerbs = sets.NewString("get", "list", "watch")
+
+// WithReadOnly rejects every request that is not a read with a 403, for
+// exposing a listener to networks that must never mutate state. API requests
+// are classified by their resolved verb rather than the HTTP method, so
+// subresources and the /watch/ path form are handled correctly; non-resource
+// paths fall back to allowing only GET and HEAD.
+func WithReadOnly(handler http.Handler, resolver *RequestInfoResolver) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		allowed := false
+		if requestInfo, err := resolver.GetRequestInfo(req); err == nil && requestInfo.IsResourceRequest {
+			allowed = readOnlyVerbs.Has(requestInfo.Verb)
+		} else {
+			allowed = req.Method == "GET" || req.Method == "HEAD"
+		}
+		if !allowed {
+			http.Error(w, "This endpoint is read-only.", http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// WithRequestBodyLimit rejects requests whose body exceeds maxBytes with
+// 413 Request Entity Too Large, so a single huge object cannot exhaust
+// memory during decode. Bodies without a declared Content-Length are capped
+// while being read. Long-running requests (watches, proxies) are exempt.
+func WithRequestBodyLimit(handler http.Handler, maxBytes int64, longRunningRequestRE *regexp.Regexp) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if longRunningRequestRE.MatchString(req.URL.Path) || isWatchRequest(req) {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		if req.ContentLength > maxBytes {
+			http.Error(w, "Request entity too large.", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if req.Body != nil {
+			req.Body = http.MaxBytesReader(w, req.Body, maxBytes)
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// isWatchRequest returns true for requests that establish a watch stream,
+// either via the ?watch=true parameter or the /watch/ path form.
+func isWatchRequest(req *http.Request) bool {
+	if req.URL.Query().Get("watch") == "true" {
+		return true
+	}
+	for _, part := range splitPath(req.URL.Path) {
+		if part == "watch" {
+			return true
+		}
+	}
+	return false
+}
+
+// WithHTTP10WatchRejection rejects watch requests from HTTP/1.0 clients with
+// a clear 400 error. HTTP/1.0 has neither keep-alive nor chunked transfer
+// encoding, so a watch stream cannot be delivered and the connection would
+// otherwise just hang. Simple requests from HTTP/1.0 clients are still
+// served.
+func WithHTTP10WatchRejection(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.ProtoMajor == 1 && req.ProtoMinor == 0 && isWatchRequest(req) {
+			http.Error(w, "Watch requests require HTTP/1.1 or newer: HTTP/1.0 cannot carry a streaming response.", http.StatusBadRequest)
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// PathRewrite maps a deprecated path prefix to its replacement, used to keep
+// old clients working across a group-version rename.
+type PathRewrite struct {
+	// OldPrefix is the deprecated path prefix requests may still arrive on.
+	OldPrefix string
+	// NewPrefix replaces OldPrefix before the request is routed.
+	NewPrefix string
+}
+
+// WithPathRewrites rewrites request paths matching a configured prefix to
+// their new form before any routing or authorization happens, so auth and
+// handlers consistently see the real resource. A deprecation warning header
+// is emitted for rewritten requests so clients know to migrate.
+func WithPathRewrites(handler http.Handler, rewrites []PathRewrite) http.Handler {
+	if len(rewrites) == 0 {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		for _, rewrite := range rewrites {
+			if req.URL.Path == rewrite.OldPrefix || strings.HasPrefix(req.URL.Path, rewrite.OldPrefix+"/") {
+				req.URL.Path = rewrite.NewPrefix + strings.TrimPrefix(req.URL.Path, rewrite.OldPrefix)
+				w.Header().Add("Warning", fmt.Sprintf("299 - \"%s is deprecated; use %s\"", rewrite.OldPrefix, rewrite.NewPrefix))
+				break
+			}
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// WithClientCertExpiryCheck rejects requests whose client certificate is
+// within rejectGrace of expiry, and emits a "Warning: 299" header when the
+// certificate is within warnGrace of expiry, so clients rotate credentials
+// proactively instead of failing abruptly. Either grace may be zero to
+// disable that behavior. Requests without a client certificate pass through
+// untouched.
+func WithClientCertExpiryCheck(handler http.Handler, rejectGrace, warnGrace time.Duration) http.Handler {
+	if rejectGrace == 0 && warnGrace == 0 {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 {
+			notAfter := req.TLS.PeerCertificates[0].NotAfter
+			remaining := notAfter.Sub(time.Now())
+			if rejectGrace > 0 && remaining < rejectGrace {
+				http.Error(w, "Client certificate is about to expire; rotate credentials.", http.StatusUnauthorized)
+				return
+			}
+			if warnGrace > 0 && remaining < warnGrace {
+				w.Header().Add("Warning", fmt.Sprintf("299 - \"client certificate expires at %s; rotate credentials\"", notAfter.UTC().Format(time.RFC3339)))
+			}
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// WithFramingCheck rejects requests carrying ambiguous message-framing
+// headers: multiple differing Content-Length values, or a Content-Length
+// combined with a Transfer-Encoding. Intermediaries that resolve these
+// conflicts differently than the server does enable request smuggling, so we
+// refuse to interpret such requests at all.
+func WithFramingCheck(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		contentLengths := req.Header["Content-Length"]
+		for i := 1; i < len(contentLengths); i++ {
+			if contentLengths[i] != contentLengths[0] {
+				http.Error(w, "Rejected: conflicting Content-Length headers.", http.StatusBadRequest)
+				return
+			}
+		}
+		if len(contentLengths) > 0 && len(req.TransferEncoding) > 0 {
+			http.Error(w, "Rejected: both Content-Length and Transfer-Encoding present.", http.StatusBadRequest)
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// StatusMisdirectedRequest is defined by RFC 7540 section 9.1.2 for requests
+// that reach a server not configured to produce a response for the requested
+// authority. net/http does not provide the constant.
+const StatusMisdirectedRequest = 421
+
+// WithMisdirectedRequestCheck rejects TLS requests whose SNI server name is
+// not among the names the server's certificates are valid for. HTTP/2 clients
+// may coalesce connections across hostnames resolving to the same address;
+// responding 421 tells such a client to retry on a fresh connection rather
+// than be served with the wrong identity. servedNames must be lowercase;
+// wildcard entries of the form "*.example.com" are honored.
+func WithMisdirectedRequestCheck(handler http.Handler, servedNames sets.String) http.Handler {
+	if servedNames.Len() == 0 {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.TLS != nil && len(req.TLS.ServerName) > 0 && !matchesServedName(servedNames, req.TLS.ServerName) {
+			http.Error(w, "Misdirected request: this server does not serve the requested host.", StatusMisdirectedRequest)
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+func matchesServedName(servedNames sets.String, name string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if servedNames.Has(name) {
+		return true
+	}
+	if i := strings.Index(name, "."); i > 0 && servedNames.Has("*"+name[i:]) {
+		return true
+	}
+	return false
+}
+
+// WithHostAllowlist rejects requests whose Host header names a host outside
+// the allowlist, closing DNS rebinding and host-header manipulation vectors
+// when the server sits behind a misconfigured proxy. Entries name a bare host
+// or host:port; a request matches when either form matches. An empty
+// allowlist keeps the current allow-all behavior. Requests without a Host
+// header are rejected with 400, requests for other hosts with 421.
+func WithHostAllowlist(handler http.Handler, allowedHosts []string) http.Handler {
+	if len(allowedHosts) == 0 {
+		return handler
+	}
+	allowed := sets.String{}
+	for _, host := range allowedHosts {
+		allowed.Insert(strings.ToLower(host))
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		host := strings.ToLower(req.Host)
+		if len(host) == 0 {
+			http.Error(w, "Missing required Host header.", http.StatusBadRequest)
+			return
+		}
+		if !allowed.Has(host) {
+			// Also accept a match on the host without its port.
+			hostOnly, _, err := net.SplitHostPort(host)
+			if err != nil || !allowed.Has(hostOnly) {
+				http.Error(w, "Misdirected request: this server does not serve the requested host.", StatusMisdirectedRequest)
+				return
+			}
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
 // RecoverPanics wraps an http Handler to recover and log panics.
 func RecoverPanics(handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
@@ -130,6 +490,20 @@ func RecoverPanics(handler http.Handler) http.Handler {
 	})
 }
 
+// WithTimeoutHeader reports the server-side timeout applied to each request in
+// an X-Timeout-Seconds response header, so clients can choose a compatible
+// client-side timeout instead of cancelling prematurely. timeoutFunc returns
+// the timeout that will be applied to the request, or zero when none applies
+// (long-running requests).
+func WithTimeoutHeader(handler http.Handler, timeoutFunc func(*http.Request) time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if timeout := timeoutFunc(req); timeout > 0 {
+			w.Header().Set("X-Timeout-Seconds", strconv.Itoa(int(timeout/time.Second)))
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
 // TimeoutHandler returns an http.Handler that runs h with a timeout
 // determined by timeoutFunc. The new http.Handler calls h.ServeHTTP to handle
 // each request, but if a call runs for longer than its time limit, the
@@ -168,6 +542,320 @@ func (t *timeoutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// TimeoutClamp shortens per-request timeouts once the server begins draining,
+// so that requests which would normally run for their full timeout finish
+// within the remaining grace window instead of blocking shutdown.
+type TimeoutClamp struct {
+	mu       sync.Mutex
+	deadline time.Time
+}
+
+// StartDraining records the shutdown deadline. Timeouts clamped after this
+// call will not extend beyond now+grace.
+func (c *TimeoutClamp) StartDraining(grace time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = time.Now().Add(grace)
+}
+
+// Clamp returns the given timeout, shortened to the remaining drain window if
+// draining has started. Once the window is exhausted a minimal non-zero
+// timeout is returned so in-flight requests are terminated promptly.
+func (c *TimeoutClamp) Clamp(timeout time.Duration) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.deadline.IsZero() {
+		return timeout
+	}
+	remaining := c.deadline.Sub(time.Now())
+	if remaining <= 0 {
+		return time.Millisecond
+	}
+	if remaining < timeout {
+		return remaining
+	}
+	return timeout
+}
+
+// MaintenanceMode is a runtime toggle that short-circuits API requests with a
+// 503 and a custom message during planned maintenance windows, while leaving
+// operational endpoints alive. It is distinct from a read-only mode (which
+// would still allow reads) and from shutdown (which terminates connections).
+type MaintenanceMode struct {
+	mu         sync.RWMutex
+	on         bool
+	message    string
+	retryAfter time.Duration
+}
+
+// Set turns maintenance mode on or off, with the message and Retry-After
+// interval returned to rejected clients.
+func (m *MaintenanceMode) Set(on bool, message string, retryAfter time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.on = on
+	m.message = message
+	m.retryAfter = retryAfter
+}
+
+func (m *MaintenanceMode) state() (bool, string, time.Duration) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.on, m.message, m.retryAfter
+}
+
+// WithMaintenanceMode rejects requests with a 503 while maintenance mode is
+// on, except for paths under the exempt prefixes (health checks, metrics and
+// the toggle endpoint itself).
+func WithMaintenanceMode(handler http.Handler, mode *MaintenanceMode, exemptPrefixes []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if on, message, retryAfter := mode.state(); on {
+			exempt := false
+			for _, prefix := range exemptPrefixes {
+				if strings.HasPrefix(req.URL.Path, prefix) {
+					exempt = true
+					break
+				}
+			}
+			if !exempt {
+				if len(message) == 0 {
+					message = "The server is under maintenance, please try again later."
+				}
+				if retryAfter > 0 {
+					w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter/time.Second)))
+				}
+				http.Error(w, message, http.StatusServiceUnavailable)
+				return
+			}
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// WithStandbyMode rejects mutating requests while the server is a warm
+// standby, directing clients at the current leader via the X-Leader header.
+// Reads keep being served, so a standby still provides load balancer
+// redundancy.
+func WithStandbyMode(handler http.Handler, role func() (active bool, leader string)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if active, leader := role(); !active {
+			switch req.Method {
+			case "GET", "HEAD", "OPTIONS":
+			default:
+				if len(leader) > 0 {
+					w.Header().Set("X-Leader", leader)
+				}
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, "This apiserver is a warm standby and does not accept writes; retry against the active instance.", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// mutatingVerbs are the HTTP methods that carry a request body the server
+// must decode.
+var mutatingVerbs = sets.NewString("POST", "PUT", "PATCH")
+
+// WithContentTypeCheck handles mutating requests that carry a body but no
+// Content-Type header, which the server would otherwise guess at and can
+// silently misparse. When strict is true such requests are rejected with a
+// 415; otherwise, when defaultContentType is non-empty, it is filled in
+// before decoding. With neither configured the current guessing behavior is
+// preserved.
+func WithContentTypeCheck(handler http.Handler, defaultContentType string, strict bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if mutatingVerbs.Has(req.Method) && req.ContentLength != 0 && len(req.Header.Get("Content-Type")) == 0 {
+			if strict {
+				http.Error(w, "A Content-Type header is required for requests with a body.", http.StatusUnsupportedMediaType)
+				return
+			}
+			if len(defaultContentType) > 0 {
+				req.Header.Set("Content-Type", defaultContentType)
+			}
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// CertAttributeExtractor derives attributes (organizational units, custom
+// extensions, etc.) from a verified client certificate for use by downstream
+// authorization and audit.
+type CertAttributeExtractor func(*x509.Certificate) map[string]string
+
+// WithCertAttributes stores the attributes the extractor derives from the
+// client certificate in the request context, so cert-based identities can
+// carry richer metadata than the common name alone. Requests without a client
+// certificate pass through untouched.
+func WithCertAttributes(handler http.Handler, mapper api.RequestContextMapper, extractor CertAttributeExtractor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 {
+			if attributes := extractor(req.TLS.PeerCertificates[0]); len(attributes) > 0 {
+				if ctx, ok := mapper.Get(req); ok {
+					if err := mapper.Update(req, api.WithCertAttributes(ctx, attributes)); err != nil {
+						glog.Errorf("Unable to store client certificate attributes in request context: %v", err)
+					}
+				}
+			}
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// WithRequestID ensures every request carries a correlation ID: the value of
+// the given header when the client (or an edge proxy) supplied one, or a
+// generated UUID otherwise. The ID is stored in the request context for
+// downstream handlers and echoed back in the response so logs can be
+// correlated across services.
+func WithRequestID(handler http.Handler, header string, mapper api.RequestContextMapper) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(header)
+		if len(id) == 0 {
+			id = string(util.NewUUID())
+			req.Header.Set(header, id)
+		}
+		if ctx, ok := mapper.Get(req); ok {
+			if err := mapper.Update(req, api.WithRequestID(ctx, id)); err != nil {
+				glog.Errorf("Unable to store request ID in request context: %v", err)
+			}
+		}
+		w.Header().Set(header, id)
+		handler.ServeHTTP(w, req)
+	})
+}
+
+var tlsVersionNames = map[uint16]string{
+	tls.VersionSSL30: "SSL3.0",
+	tls.VersionTLS10: "TLS1.0",
+	tls.VersionTLS11: "TLS1.1",
+	tls.VersionTLS12: "TLS1.2",
+}
+
+var tlsCipherSuiteNames = map[uint16]string{
+	tls.TLS_RSA_WITH_RC4_128_SHA:                "TLS_RSA_WITH_RC4_128_SHA",
+	tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA:           "TLS_RSA_WITH_3DES_EDE_CBC_SHA",
+	tls.TLS_RSA_WITH_AES_128_CBC_SHA:            "TLS_RSA_WITH_AES_128_CBC_SHA",
+	tls.TLS_RSA_WITH_AES_256_CBC_SHA:            "TLS_RSA_WITH_AES_256_CBC_SHA",
+	tls.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA:        "TLS_ECDHE_ECDSA_WITH_RC4_128_SHA",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA:    "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA:    "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA:          "TLS_ECDHE_RSA_WITH_RC4_128_SHA",
+	tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA:     "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA:      "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA:      "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256:   "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256: "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+}
+
+// tlsName looks up an id in the given name table, falling back to the hex id
+// for values the table does not know about.
+func tlsName(names map[uint16]string, id uint16) string {
+	if name, ok := names[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", id)
+}
+
+// WithTLSNegotiationMetrics records the negotiated TLS version and cipher
+// suite of each request served over TLS, as a metric labeled by both and a
+// low-verbosity log line. Operators use the distribution to plan deprecating
+// weak versions and ciphers. Requests without TLS state pass through
+// untouched.
+func WithTLSNegotiationMetrics(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.TLS != nil {
+			version := tlsName(tlsVersionNames, req.TLS.Version)
+			cipher := tlsName(tlsCipherSuiteNames, req.TLS.CipherSuite)
+			metrics.MonitorTLSNegotiation(version, cipher)
+			glog.V(6).Infof("TLS connection from %s negotiated %s with %s", req.RemoteAddr, version, cipher)
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// RequestDrainer tracks in-flight requests separated into short requests and
+// long-running requests (watches, proxies), so shutdown can drain them in
+// order: short requests finish quickly and are waited on first, while watches
+// keep the remainder of the grace window.
+type RequestDrainer struct {
+	mu       sync.Mutex
+	draining bool
+	short    int
+	long     int
+}
+
+func (d *RequestDrainer) register(longRunning bool) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	if longRunning {
+		d.long++
+	} else {
+		d.short++
+	}
+	return true
+}
+
+func (d *RequestDrainer) finish(longRunning bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if longRunning {
+		d.long--
+	} else {
+		d.short--
+	}
+}
+
+func (d *RequestDrainer) pending(longRunning bool) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if longRunning {
+		return d.long
+	}
+	return d.short
+}
+
+// Drain stops admitting new requests, waits up to shortGrace for short
+// requests to complete, and then waits up to watchGrace for long-running
+// requests. Draining short requests first lets them finish promptly while
+// watch connections get the maximum remaining time.
+func (d *RequestDrainer) Drain(shortGrace, watchGrace time.Duration) {
+	d.mu.Lock()
+	d.draining = true
+	d.mu.Unlock()
+	d.waitFor(false, shortGrace)
+	d.waitFor(true, watchGrace)
+}
+
+func (d *RequestDrainer) waitFor(longRunning bool, grace time.Duration) {
+	deadline := time.Now().Add(grace)
+	for time.Now().Before(deadline) {
+		if d.pending(longRunning) == 0 {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// WithRequestDraining registers each request with the drainer under its pool
+// (short or long-running), and rejects new requests with a 503 once draining
+// has begun.
+func WithRequestDraining(handler http.Handler, longRunningRequestRE *regexp.Regexp, drainer *RequestDrainer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		longRunning := longRunningRequestRE.MatchString(r.URL.Path)
+		if !drainer.register(longRunning) {
+			w.Header().Set("Retry-After", RetryAfter)
+			http.Error(w, "The server is shutting down, please try another instance.", http.StatusServiceUnavailable)
+			return
+		}
+		defer drainer.finish(longRunning)
+		handler.ServeHTTP(w, r)
+	})
+}
+
 type timeoutWriter interface {
 	http.ResponseWriter
 	timeout(string)
@@ -300,8 +988,9 @@ func (tw *closeHijackTimeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error
 // Simple CORS implementation that wraps an http Handler
 // For a more detailed implementation use https://github.com/martini-contrib/cors
 // or implement CORS at your proxy layer
-// Pass nil for allowedMethods and allowedHeaders to use the defaults
-func CORS(handler http.Handler, allowedOriginPatterns []*regexp.Regexp, allowedMethods []string, allowedHeaders []string, allowCredentials string) http.Handler {
+// Pass nil for allowedMethods and allowedHeaders to use the defaults.
+// Pass a zero maxAge to omit the Access-Control-Max-Age header.
+func CORS(handler http.Handler, allowedOriginPatterns []*regexp.Regexp, allowedMethods []string, allowedHeaders []string, allowCredentials string, maxAge time.Duration) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		origin := req.Header.Get("Origin")
 		if origin != "" {
@@ -323,6 +1012,11 @@ func CORS(handler http.Handler, allowedOriginPatterns []*regexp.Regexp, allowedM
 				w.Header().Set("Access-Control-Allow-Methods", strings.Join(allowedMethods, ", "))
 				w.Header().Set("Access-Control-Allow-Headers", strings.Join(allowedHeaders, ", "))
 				w.Header().Set("Access-Control-Allow-Credentials", allowCredentials)
+				if maxAge > 0 {
+					// Let browsers cache preflight results instead of
+					// re-sending OPTIONS before every request.
+					w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge/time.Second)))
+				}
 
 				// Stop here if its a preflight OPTIONS request
 				if req.Method == "OPTIONS" {
@@ -384,14 +1078,73 @@ func (r *requestAttributeGetter) GetAttribs(req *http.Request) authorizer.Attrib
 	return &attribs
 }
 
+// DeniedAuthorizationAuditFunc receives the attributes and the authorizer's
+// reason for every request rejected by the authorization check.
+type DeniedAuthorizationAuditFunc func(attribs authorizer.Attributes, reason string)
+
+// LogDeniedAuthorization is the default audit sink for authorization denials;
+// it records the denied attributes and the authorizer's reason in the log.
+func LogDeniedAuthorization(attribs authorizer.Attributes, reason string) {
+	glog.Warningf("Authorization denied (user=%q, groups=%v, verb=%q, resource=%q, namespace=%q): %s",
+		attribs.GetUserName(), attribs.GetGroups(), attribs.GetVerb(), attribs.GetResource(), attribs.GetNamespace(), reason)
+}
+
+// WithAudit writes one line per completed request to out, recording the
+// authenticated user, verb, resource, namespace, source IP and response
+// code. It must wrap a handler chain that performs authentication, so the
+// user identity is in the request context by the time the line is written
+// after the response.
+func WithAudit(handler http.Handler, getAttribs RequestAttributeGetter, out io.Writer) http.Handler {
+	var lock sync.Mutex
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		recorder := &auditResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(recorder, req)
+		attribs := getAttribs.GetAttribs(req)
+		sourceIP := req.RemoteAddr
+		if host, _, err := net.SplitHostPort(sourceIP); err == nil {
+			sourceIP = host
+		}
+		line := fmt.Sprintf("%s AUDIT: user=%q verb=%q resource=%q namespace=%q sourceIP=%q code=%d\n",
+			time.Now().Format(time.RFC3339), attribs.GetUserName(), attribs.GetVerb(), attribs.GetResource(), attribs.GetNamespace(), sourceIP, recorder.status)
+		lock.Lock()
+		defer lock.Unlock()
+		if _, err := out.Write([]byte(line)); err != nil {
+			glog.Errorf("Unable to write audit line: %v", err)
+		}
+	})
+}
+
+// auditResponseWriter captures the response status code for the audit line.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
 // WithAuthorizationCheck passes all authorized requests on to handler, and returns a forbidden error otherwise.
 func WithAuthorizationCheck(handler http.Handler, getAttribs RequestAttributeGetter, a authorizer.Authorizer) http.Handler {
+	return WithAuthorizationCheckAndAudit(handler, getAttribs, a, nil)
+}
+
+// WithAuthorizationCheckAndAudit behaves like WithAuthorizationCheck, but
+// additionally reports every denial to the given audit function. Denials are
+// audited unconditionally — independent of any per-resource audit policy —
+// because security reviews need a complete record of rejected access.
+func WithAuthorizationCheckAndAudit(handler http.Handler, getAttribs RequestAttributeGetter, a authorizer.Authorizer, audit DeniedAuthorizationAuditFunc) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		err := a.Authorize(getAttribs.GetAttribs(req))
+		attribs := getAttribs.GetAttribs(req)
+		err := a.Authorize(attribs)
 		if err == nil {
 			handler.ServeHTTP(w, req)
 			return
 		}
+		if audit != nil {
+			audit(attribs, err.Error())
+		}
 		forbidden(w, req)
 	})
 }
@@ -17,10 +17,12 @@ limitations under the License.
 package apiserver
 
 import (
+	"crypto/tls"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
 	"regexp"
 	"strings"
@@ -33,6 +35,7 @@ import (
 	"k8s.io/kubernetes/pkg/api/testapi"
 	"k8s.io/kubernetes/pkg/apis/extensions"
 	"k8s.io/kubernetes/pkg/auth/authorizer"
+	"k8s.io/kubernetes/pkg/auth/user"
 	"k8s.io/kubernetes/pkg/util/sets"
 )
 
@@ -464,3 +467,487 @@ func TestGetNonAPIRequestInfo(t *testing.T) {
 		}
 	}
 }
+
+func TestTimeoutClamp(t *testing.T) {
+	clamp := &TimeoutClamp{}
+	if actual := clamp.Clamp(time.Minute); actual != time.Minute {
+		t.Errorf("expected timeout to pass through before draining, got %v", actual)
+	}
+	clamp.StartDraining(time.Second)
+	if actual := clamp.Clamp(time.Minute); actual > time.Second {
+		t.Errorf("expected timeout clamped to the drain window, got %v", actual)
+	}
+	clamp.StartDraining(0)
+	if actual := clamp.Clamp(time.Minute); actual > time.Millisecond {
+		t.Errorf("expected minimal timeout after the drain window expired, got %v", actual)
+	}
+}
+
+func TestWithMisdirectedRequestCheck(t *testing.T) {
+	servedNames := sets.NewString("kubernetes.default", "*.example.com")
+	testCases := []struct {
+		serverName string
+		expectCode int
+	}{
+		{"", http.StatusOK},
+		{"kubernetes.default", http.StatusOK},
+		{"KUBERNETES.DEFAULT", http.StatusOK},
+		{"api.example.com", http.StatusOK},
+		{"elsewhere.org", StatusMisdirectedRequest},
+		{"sub.api.example.com", StatusMisdirectedRequest},
+	}
+	inner := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := WithMisdirectedRequestCheck(inner, servedNames)
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		req := &http.Request{
+			Method: "GET",
+			URL:    &url.URL{Path: "/api"},
+			TLS:    &tls.ConnectionState{ServerName: tc.serverName},
+		}
+		handler.ServeHTTP(w, req)
+		if w.Code != tc.expectCode {
+			t.Errorf("SNI %q: expected status %d, got %d", tc.serverName, tc.expectCode, w.Code)
+		}
+	}
+}
+
+func TestWithFramingCheck(t *testing.T) {
+	testCases := []struct {
+		name             string
+		contentLengths   []string
+		transferEncoding []string
+		expectCode       int
+	}{
+		{"no framing headers", nil, nil, http.StatusOK},
+		{"single content length", []string{"10"}, nil, http.StatusOK},
+		{"duplicate identical content lengths", []string{"10", "10"}, nil, http.StatusOK},
+		{"conflicting content lengths", []string{"10", "20"}, nil, http.StatusBadRequest},
+		{"content length with transfer encoding", []string{"10"}, []string{"chunked"}, http.StatusBadRequest},
+		{"transfer encoding only", nil, []string{"chunked"}, http.StatusOK},
+	}
+	inner := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := WithFramingCheck(inner)
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		req := &http.Request{
+			Method:           "POST",
+			URL:              &url.URL{Path: "/api"},
+			Header:           http.Header{},
+			TransferEncoding: tc.transferEncoding,
+		}
+		if len(tc.contentLengths) > 0 {
+			req.Header["Content-Length"] = tc.contentLengths
+		}
+		handler.ServeHTTP(w, req)
+		if w.Code != tc.expectCode {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.expectCode, w.Code)
+		}
+	}
+}
+
+func TestWithPathRewrites(t *testing.T) {
+	rewrites := []PathRewrite{
+		{OldPrefix: "/apis/oldgroup", NewPrefix: "/apis/newgroup"},
+	}
+	var seenPath string
+	inner := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		seenPath = req.URL.Path
+	})
+	handler := WithPathRewrites(inner, rewrites)
+
+	testCases := []struct {
+		path         string
+		expectedPath string
+		expectWarn   bool
+	}{
+		{"/apis/oldgroup/v1/pods", "/apis/newgroup/v1/pods", true},
+		{"/apis/oldgroup", "/apis/newgroup", true},
+		{"/apis/oldgroupish/v1", "/apis/oldgroupish/v1", false},
+		{"/apis/other/v1", "/apis/other/v1", false},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		req := &http.Request{Method: "GET", URL: &url.URL{Path: tc.path}}
+		handler.ServeHTTP(w, req)
+		if seenPath != tc.expectedPath {
+			t.Errorf("%s: expected path %q, got %q", tc.path, tc.expectedPath, seenPath)
+		}
+		if warned := w.Header().Get("Warning") != ""; warned != tc.expectWarn {
+			t.Errorf("%s: expected warning=%v, got %v", tc.path, tc.expectWarn, warned)
+		}
+	}
+}
+
+func TestWithHTTP10WatchRejection(t *testing.T) {
+	testCases := []struct {
+		path       string
+		query      string
+		protoMinor int
+		expectCode int
+	}{
+		{"/api/v1/pods", "", 0, http.StatusOK},
+		{"/api/v1/pods", "watch=true", 0, http.StatusBadRequest},
+		{"/api/v1/watch/pods", "", 0, http.StatusBadRequest},
+		{"/api/v1/pods", "watch=true", 1, http.StatusOK},
+		{"/api/v1/watch/pods", "", 1, http.StatusOK},
+	}
+	inner := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := WithHTTP10WatchRejection(inner)
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		req := &http.Request{
+			Method:     "GET",
+			URL:        &url.URL{Path: tc.path, RawQuery: tc.query},
+			ProtoMajor: 1,
+			ProtoMinor: tc.protoMinor,
+		}
+		handler.ServeHTTP(w, req)
+		if w.Code != tc.expectCode {
+			t.Errorf("%s?%s HTTP/1.%d: expected status %d, got %d", tc.path, tc.query, tc.protoMinor, tc.expectCode, w.Code)
+		}
+	}
+}
+
+func TestRequestDrainer(t *testing.T) {
+	re := regexp.MustCompile("(/watch/)")
+	drainer := &RequestDrainer{}
+	inner := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := WithRequestDraining(inner, re, drainer)
+
+	// Before draining, requests of both types are admitted.
+	for _, path := range []string{"/api/v1/pods", "/api/v1/watch/pods"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, &http.Request{Method: "GET", URL: &url.URL{Path: path}})
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status %d, got %d", path, http.StatusOK, w.Code)
+		}
+	}
+	if pending := drainer.pending(false); pending != 0 {
+		t.Errorf("expected no pending short requests, got %d", pending)
+	}
+
+	// After draining, new requests are rejected.
+	drainer.Drain(0, 0)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, &http.Request{Method: "GET", URL: &url.URL{Path: "/api/v1/pods"}})
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
+
+func TestWithContentTypeCheck(t *testing.T) {
+	testCases := []struct {
+		method      string
+		contentType string
+		strict      bool
+		defaultType string
+		expectCode  int
+		expectType  string
+	}{
+		// Reads are never affected.
+		{"GET", "", true, "", http.StatusOK, ""},
+		// Strict mode rejects writes without a Content-Type.
+		{"POST", "", true, "", http.StatusUnsupportedMediaType, ""},
+		{"PUT", "", true, "", http.StatusUnsupportedMediaType, ""},
+		{"POST", "application/json", true, "", http.StatusOK, "application/json"},
+		// A default fills in the missing header.
+		{"POST", "", false, "application/json", http.StatusOK, "application/json"},
+		{"POST", "application/yaml", false, "application/json", http.StatusOK, "application/yaml"},
+		// With neither configured, requests pass through untouched.
+		{"POST", "", false, "", http.StatusOK, ""},
+	}
+	for i, tc := range testCases {
+		seenType := ""
+		inner := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			seenType = req.Header.Get("Content-Type")
+			w.WriteHeader(http.StatusOK)
+		})
+		handler := WithContentTypeCheck(inner, tc.defaultType, tc.strict)
+		req, err := http.NewRequest(tc.method, "http://localhost/api/v1/pods", strings.NewReader("{}"))
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+		req.Header.Del("Content-Type")
+		if len(tc.contentType) > 0 {
+			req.Header.Set("Content-Type", tc.contentType)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tc.expectCode {
+			t.Errorf("%d: expected status %d, got %d", i, tc.expectCode, w.Code)
+			continue
+		}
+		if w.Code == http.StatusOK && seenType != tc.expectType {
+			t.Errorf("%d: expected Content-Type %q, got %q", i, tc.expectType, seenType)
+		}
+	}
+}
+
+func TestWithMaintenanceMode(t *testing.T) {
+	mode := &MaintenanceMode{}
+	inner := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := WithMaintenanceMode(inner, mode, []string{"/healthz", "/metrics"})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, &http.Request{Method: "GET", URL: &url.URL{Path: path}})
+		return w
+	}
+
+	// Off: everything passes.
+	if w := serve("/api/v1/pods"); w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// On: API requests get a 503 with the message and Retry-After, while
+	// exempt paths stay reachable.
+	mode.Set(true, "back at noon", 30*time.Second)
+	w := serve("/api/v1/pods")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if retryAfter := w.Header().Get("Retry-After"); retryAfter != "30" {
+		t.Errorf("expected Retry-After %q, got %q", "30", retryAfter)
+	}
+	if !strings.Contains(w.Body.String(), "back at noon") {
+		t.Errorf("expected message in body, got %q", w.Body.String())
+	}
+	for _, path := range []string{"/healthz", "/healthz/ping", "/metrics"} {
+		if w := serve(path); w.Code != http.StatusOK {
+			t.Errorf("%s: expected status %d, got %d", path, http.StatusOK, w.Code)
+		}
+	}
+
+	// Toggled back off.
+	mode.Set(false, "", 0)
+	if w := serve("/api/v1/pods"); w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestWithHostAllowlist(t *testing.T) {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	testCases := []struct {
+		allowed  []string
+		host     string
+		expected int
+	}{
+		// Empty allowlist preserves allow-all behavior.
+		{nil, "evil.example.com", http.StatusOK},
+		{[]string{"kubernetes.example.com"}, "kubernetes.example.com", http.StatusOK},
+		// Host headers match case-insensitively.
+		{[]string{"kubernetes.example.com"}, "Kubernetes.Example.Com", http.StatusOK},
+		// A bare-host entry matches requests carrying a port.
+		{[]string{"kubernetes.example.com"}, "kubernetes.example.com:6443", http.StatusOK},
+		{[]string{"kubernetes.example.com:6443"}, "kubernetes.example.com:6443", http.StatusOK},
+		{[]string{"kubernetes.example.com"}, "evil.example.com", StatusMisdirectedRequest},
+		{[]string{"kubernetes.example.com"}, "", http.StatusBadRequest},
+	}
+	for i, tc := range testCases {
+		handler := WithHostAllowlist(inner, tc.allowed)
+		req, err := http.NewRequest("GET", "http://localhost/api/v1/pods", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		req.Host = tc.host
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tc.expected {
+			t.Errorf("%d: host %q with allowlist %v: expected %d, got %d", i, tc.host, tc.allowed, tc.expected, w.Code)
+		}
+	}
+}
+
+func TestWithReadOnly(t *testing.T) {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	resolver := &RequestInfoResolver{
+		APIPrefixes:          sets.NewString("api", "apis"),
+		GrouplessAPIPrefixes: sets.NewString("api"),
+	}
+	handler := WithReadOnly(inner, resolver)
+
+	testCases := []struct {
+		method   string
+		path     string
+		expected int
+	}{
+		{"GET", "/api/v1/pods", http.StatusOK},
+		{"GET", "/api/v1/watch/pods", http.StatusOK},
+		{"GET", "/api/v1/namespaces/default/pods/foo", http.StatusOK},
+		{"POST", "/api/v1/namespaces/default/pods", http.StatusForbidden},
+		{"PUT", "/api/v1/namespaces/default/pods/foo", http.StatusForbidden},
+		{"DELETE", "/api/v1/namespaces/default/pods/foo", http.StatusForbidden},
+		// Non-resource paths only allow GET and HEAD.
+		{"GET", "/healthz", http.StatusOK},
+		{"POST", "/healthz", http.StatusForbidden},
+	}
+	for i, tc := range testCases {
+		req, err := http.NewRequest(tc.method, "http://localhost"+tc.path, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tc.expected {
+			t.Errorf("%d: %s %s: expected %d, got %d", i, tc.method, tc.path, tc.expected, w.Code)
+		}
+	}
+}
+
+func TestWithRequestBodyLimit(t *testing.T) {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, err := ioutil.ReadAll(req.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := WithRequestBodyLimit(inner, 16, regexp.MustCompile(".*\\/watch$"))
+
+	testCases := []struct {
+		path     string
+		body     string
+		expected int
+	}{
+		{"/api/v1/pods", "small body", http.StatusOK},
+		{"/api/v1/pods", strings.Repeat("x", 64), http.StatusRequestEntityTooLarge},
+		// Long-running paths are exempt from the limit.
+		{"/api/v1/pods/watch", strings.Repeat("x", 64), http.StatusOK},
+	}
+	for i, tc := range testCases {
+		req, err := http.NewRequest("POST", "http://localhost"+tc.path, strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tc.expected {
+			t.Errorf("%d: body of %d bytes: expected %d, got %d", i, len(tc.body), tc.expected, w.Code)
+		}
+	}
+}
+
+func TestWithRequestID(t *testing.T) {
+	mapper := api.NewRequestContextMapper()
+	var contextID string
+	inner := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		contextID = ""
+		if ctx, ok := mapper.Get(req); ok {
+			contextID, _ = api.RequestIDFrom(ctx)
+		}
+	})
+	handler, err := api.NewRequestContextFilter(mapper, WithRequestID(inner, "X-Request-Id", mapper))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	// A supplied ID is stored in the context and echoed back.
+	req, err := http.NewRequest("GET", server.URL, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req.Header.Set("X-Request-Id", "abc-123")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if echoed := resp.Header.Get("X-Request-Id"); echoed != "abc-123" {
+		t.Errorf("expected the supplied ID to be echoed, got %q", echoed)
+	}
+	if contextID != "abc-123" {
+		t.Errorf("expected the supplied ID in the request context, got %q", contextID)
+	}
+
+	// A missing ID is generated.
+	resp, err = http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	generated := resp.Header.Get("X-Request-Id")
+	if len(generated) == 0 {
+		t.Errorf("expected a generated ID to be echoed")
+	}
+	if contextID != generated {
+		t.Errorf("expected the generated ID %q in the request context, got %q", generated, contextID)
+	}
+}
+
+func TestMaxWatchesPerUser(t *testing.T) {
+	mapper := api.NewRequestContextMapper()
+
+	block := make(chan struct{})
+	started := make(chan struct{}, 8)
+	inner := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("watch") == "true" {
+			started <- struct{}{}
+			<-block
+		}
+	})
+	limited := MaxWatchesPerUser(inner, 1, mapper)
+	// Stand in for the authenticator: attach the user named by ?user=.
+	authed := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if ctx, ok := mapper.Get(req); ok {
+			mapper.Update(req, api.WithUser(ctx, &user.DefaultInfo{Name: req.URL.Query().Get("user")}))
+		}
+		limited.ServeHTTP(w, req)
+	})
+	handler, err := api.NewRequestContextFilter(mapper, authed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	responses := sync.WaitGroup{}
+	responses.Add(1)
+	go func() {
+		defer responses.Done()
+		if err := expectHTTP(server.URL+"/pods?watch=true&user=alice", http.StatusOK); err != nil {
+			t.Error(err)
+		}
+	}()
+	<-started
+
+	// alice is at her limit; bob and alice's non-watch requests are not.
+	if err := expectHTTP(server.URL+"/pods?watch=true&user=alice", errors.StatusTooManyRequests); err != nil {
+		t.Error(err)
+	}
+	if err := expectHTTP(server.URL+"/pods?user=alice", http.StatusOK); err != nil {
+		t.Error(err)
+	}
+	responses.Add(1)
+	go func() {
+		defer responses.Done()
+		if err := expectHTTP(server.URL+"/pods?watch=true&user=bob", http.StatusOK); err != nil {
+			t.Error(err)
+		}
+	}()
+	<-started
+
+	// Releasing the watches frees alice's slot again.
+	close(block)
+	responses.Wait()
+	if err := expectHTTP(server.URL+"/pods?watch=true&user=alice", http.StatusOK); err != nil {
+		t.Error(err)
+	}
+}
@@ -17,15 +17,18 @@ limitations under the License.
 package apiserver
 
 import (
+	"fmt"
 	"net/http"
-	"sort"
+	"strings"
 
 	"k8s.io/kubernetes/pkg/api/unversioned"
-
-	"github.com/emicklei/go-restful"
 )
 
-func IndexHandler(container *restful.Container, muxHelper *MuxHelper) func(http.ResponseWriter, *http.Request) {
+// IndexHandler serves a list of the handled paths, as reported by
+// listedPaths, at "/" and "/index.html". Clients get a machine-readable JSON
+// list; browsers (an Accept header naming text/html but not application/json)
+// get an HTML listing of links instead.
+func IndexHandler(listedPaths func() []string) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		status := http.StatusOK
 		if r.URL.Path != "/" && r.URL.Path != "/index.html" {
@@ -33,14 +36,16 @@ func IndexHandler(container *restful.Container, muxHelper *MuxHelper) func(http.
 			// We want to to return a 404 status with a list of all valid paths, incase of an invalid URL request.
 			status = http.StatusNotFound
 		}
-		var handledPaths []string
-		// Extract the paths handled using restful.WebService
-		for _, ws := range container.RegisteredWebServices() {
-			handledPaths = append(handledPaths, ws.RootPath())
+		paths := listedPaths()
+		accept := r.Header.Get("Accept")
+		if strings.Contains(accept, "text/html") && !strings.Contains(accept, "application/json") {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(status)
+			for _, path := range paths {
+				fmt.Fprintf(w, "<a href=%q>%s</a><br/>\n", path, path)
+			}
+			return
 		}
-		// Extract the paths handled using mux handler.
-		handledPaths = append(handledPaths, muxHelper.RegisteredPaths...)
-		sort.Strings(handledPaths)
-		writeRawJSON(status, unversioned.RootPaths{Paths: handledPaths}, w)
+		writeRawJSON(status, unversioned.RootPaths{Paths: paths}, w)
 	}
 }
@@ -35,15 +35,12 @@ var (
 		},
 		[]string{"verb", "resource", "client", "code"},
 	)
-	requestLatencies = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name: "apiserver_request_latencies",
-			Help: "Response latency distribution in microseconds for each verb, resource and client.",
-			// Use buckets ranging from 125 ms to 8 seconds.
-			Buckets: prometheus.ExponentialBuckets(125000, 2.0, 7),
-		},
-		[]string{"verb", "resource"},
-	)
+	// DefaultLatencyBuckets are the default buckets (in microseconds) for the
+	// request latency histogram: fine-grained below 100ms, where most
+	// apiserver requests complete, and coarser up to 8 seconds.
+	DefaultLatencyBuckets = []float64{1000, 2000, 4000, 8000, 16000, 32000, 64000, 125000, 250000, 500000, 1000000, 2000000, 4000000, 8000000}
+
+	requestLatencies = newRequestLatencies(DefaultLatencyBuckets)
 	requestLatenciesSummary = prometheus.NewSummaryVec(
 		prometheus.SummaryOpts{
 			Name: "apiserver_request_latencies_summary",
@@ -53,13 +50,124 @@ var (
 		},
 		[]string{"verb", "resource"},
 	)
+	discoveryGenerationLatencies = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name: "apiserver_discovery_generation_latencies",
+			Help: "Latency distribution in microseconds for generating the aggregated discovery document.",
+			// Use buckets ranging from 1 ms to ~1 second.
+			Buckets: prometheus.ExponentialBuckets(1000, 2.0, 10),
+		},
+	)
+	discoveryDocumentSize = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "apiserver_discovery_document_size_bytes",
+			Help: "Size in bytes of the most recently generated aggregated discovery document.",
+		},
+	)
+	handlerRequestCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "apiserver_handler_request_count",
+			Help: "Counter of requests seen by the handler chain, broken out by verb and HTTP response code.",
+		},
+		[]string{"verb", "code"},
+	)
+	handlerRequestLatencies = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "apiserver_handler_request_latencies",
+			Help:    "Latency distribution in microseconds of requests seen by the handler chain.",
+			Buckets: DefaultLatencyBuckets,
+		},
+	)
+	tlsNegotiationCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "apiserver_tls_negotiation_count",
+			Help: "Counter of requests served over TLS broken out by negotiated TLS version and cipher suite.",
+		},
+		[]string{"version", "cipher"},
+	)
 )
 
+func newRequestLatencies(buckets []float64) *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "apiserver_request_latencies",
+			Help:    "Response latency distribution in microseconds for each verb, resource and client.",
+			Buckets: buckets,
+		},
+		[]string{"verb", "resource"},
+	)
+}
+
+// SetLatencyBuckets replaces the request latency histogram's buckets, so
+// operators can align them with their SLO thresholds. The metric's name and
+// labels are unchanged, so existing dashboards keep working.
+func SetLatencyBuckets(buckets []float64) {
+	prometheus.Unregister(requestLatencies)
+	requestLatencies = newRequestLatencies(buckets)
+	prometheus.MustRegister(requestLatencies)
+}
+
 // Register all metrics.
 func Register() {
 	prometheus.MustRegister(requestCounter)
 	prometheus.MustRegister(requestLatencies)
 	prometheus.MustRegister(requestLatenciesSummary)
+	prometheus.MustRegister(discoveryGenerationLatencies)
+	prometheus.MustRegister(discoveryDocumentSize)
+	prometheus.MustRegister(tlsNegotiationCounter)
+	prometheus.MustRegister(handlerRequestCounter)
+	prometheus.MustRegister(handlerRequestLatencies)
+}
+
+// RegisterInFlightGauge ties an apiserver_inflight_requests gauge to the
+// given depth function — typically the depth of the MaxInFlightLimit
+// semaphore — so saturation of MaxRequestsInFlight can be alerted on.
+func RegisterInFlightGauge(depth func() int) {
+	prometheus.MustRegister(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "apiserver_inflight_requests",
+			Help: "Number of requests currently held by the in-flight limit semaphore.",
+		},
+		func() float64 { return float64(depth()) },
+	))
+}
+
+// InstrumentHandler counts every request passing through the handler chain by
+// verb and response code and records its latency, independent of per-route
+// monitoring, so requests rejected before routing are still visible.
+func InstrumentHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(recorder, req)
+		handlerRequestCounter.WithLabelValues(req.Method, strconv.Itoa(recorder.status)).Inc()
+		handlerRequestLatencies.Observe(float64(time.Since(start) / time.Microsecond))
+	})
+}
+
+// statusRecorder captures the response code written by the wrapped handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// MonitorTLSNegotiation counts a request served over TLS by the negotiated
+// protocol version and cipher suite, so operators can see the distribution
+// before tightening TLS requirements.
+func MonitorTLSNegotiation(version, cipher string) {
+	tlsNegotiationCounter.WithLabelValues(version, cipher).Inc()
+}
+
+// MonitorDiscovery records how long the aggregated discovery document took to
+// generate and how large it is.
+func MonitorDiscovery(generation time.Duration, sizeBytes int) {
+	discoveryGenerationLatencies.Observe(float64(generation / time.Microsecond))
+	discoveryDocumentSize.Set(float64(sizeBytes))
 }
 
 func Monitor(verb, resource *string, client string, httpCode *int, reqStart time.Time) {
@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"net/url"
 	gpath "path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -77,6 +78,40 @@ type RequestScope struct {
 	Resource    unversioned.GroupVersionResource
 	Kind        unversioned.GroupVersionKind
 	Subresource string
+
+	// CacheEnabled indicates that reads for this resource may be served from
+	// the watch cache rather than going directly to storage.
+	CacheEnabled bool
+
+	// DefaultListLimit bounds LIST responses for this resource when the
+	// client does not supply a limit. Zero leaves lists unbounded.
+	DefaultListLimit int
+
+	// EmitTimeoutHeader reports the effective server-side timeout of watch
+	// requests in an X-Timeout-Seconds response header.
+	EmitTimeoutHeader bool
+
+	// StreamingDecode decodes request bodies directly from the stream when
+	// the codec supports it, instead of buffering the full body first.
+	StreamingDecode bool
+}
+
+// setCacheStatusHeader reports on reads whether the watch cache could have
+// served the request. A resourceVersion of "0" allows a (possibly stale)
+// cached read, an empty resourceVersion forces a quorum read from storage,
+// and an exact resourceVersion bypasses the cache.
+func setCacheStatusHeader(w http.ResponseWriter, scope RequestScope, resourceVersion string) {
+	if !scope.CacheEnabled {
+		return
+	}
+	switch resourceVersion {
+	case "0":
+		w.Header().Set("X-Cache-Status", "hit")
+	case "":
+		w.Header().Set("X-Cache-Status", "miss")
+	default:
+		w.Header().Set("X-Cache-Status", "bypass")
+	}
 }
 
 // getterFunc performs a get request with the given context and object name. The request
@@ -108,6 +143,7 @@ func getResourceHandler(scope RequestScope, getter getterFunc) restful.RouteFunc
 			errorJSON(err, scope.Codec, w)
 			return
 		}
+		setCacheStatusHeader(w, scope, req.Request.URL.Query().Get("resourceVersion"))
 		write(http.StatusOK, scope.Kind.GroupVersion(), scope.Codec, result, w, req.Request)
 	}
 }
@@ -239,6 +275,13 @@ func (r *responder) Error(err error) {
 	errorJSON(err, r.scope.Codec, r.w)
 }
 
+// jitteredTimeout returns a random duration in [minTimeout, 2*minTimeout), as
+// documented for MinRequestTimeout, so long-running requests started together
+// do not all expire and reconnect in the same instant.
+func jitteredTimeout(minTimeout time.Duration) time.Duration {
+	return time.Duration(float64(minTimeout) * (rand.Float64() + 1.0))
+}
+
 // ListResource returns a function that handles retrieving a list of resources from a rest.Storage object.
 func ListResource(r rest.Lister, rw rest.Watcher, scope RequestScope, forceWatch bool, minRequestTimeout time.Duration) restful.RouteFunction {
 	return func(req *restful.Request, res *restful.Response) {
@@ -327,16 +370,51 @@ func ListResource(r rest.Lister, rw rest.Watcher, scope RequestScope, forceWatch
 				timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
 			}
 			if timeout == 0 && minRequestTimeout > 0 {
-				timeout = time.Duration(float64(minRequestTimeout) * (rand.Float64() + 1.0))
+				timeout = jitteredTimeout(minRequestTimeout)
+			}
+			if scope.EmitTimeoutHeader && timeout > 0 {
+				w.Header().Set("X-Timeout-Seconds", strconv.Itoa(int(timeout/time.Second)))
 			}
 			serveWatch(watcher, scope, w, req, timeout)
 			return
 		}
 
+		// Default unbounded lists to a page of DefaultListLimit items. An
+		// explicit ?limit overrides the default; ?limit=0 opts out of
+		// pagination entirely.
+		limit := scope.DefaultListLimit
+		if raw := req.Request.URL.Query().Get("limit"); len(raw) > 0 {
+			parsed, perr := strconv.Atoi(raw)
+			if perr != nil || parsed < 0 {
+				errorJSON(errors.NewBadRequest(fmt.Sprintf("invalid limit: %q", raw)), scope.Codec, w)
+				return
+			}
+			limit = parsed
+		}
+
 		// Log only long List requests (ignore Watch).
 		defer trace.LogIfLong(500 * time.Millisecond)
 		trace.Step("About to List from storage")
-		result, err := r.List(ctx, &opts)
+		var result runtime.Object
+		if limitedLister, ok := r.(rest.LimitedLister); ok && limit > 0 {
+			var continueToken string
+			result, continueToken, err = limitedLister.ListLimited(ctx, &opts, limit)
+			if err == nil && len(continueToken) > 0 {
+				w.Header().Set("X-Continue", continueToken)
+			}
+		} else if partialLister, ok := r.(rest.PartialResultsLister); ok && opts.TimeoutSeconds != nil && *opts.TimeoutSeconds > 0 {
+			// The storage supports returning what it has gathered when the
+			// deadline expires; surface the resume token so the client can
+			// continue rather than retrying the whole list.
+			var continueToken string
+			result, continueToken, err = partialLister.ListPartial(ctx, &opts, time.Duration(*opts.TimeoutSeconds)*time.Second)
+			if err == nil && len(continueToken) > 0 {
+				w.Header().Set("X-Continue", continueToken)
+				w.Header().Add("Warning", "299 - \"results are partial; resume the list with the returned continue token\"")
+			}
+		} else {
+			result, err = r.List(ctx, &opts)
+		}
 		if err != nil {
 			errorJSON(err, scope.Codec, w)
 			return
@@ -348,6 +426,7 @@ func ListResource(r rest.Lister, rw rest.Watcher, scope RequestScope, forceWatch
 			return
 		}
 		trace.Step("Self-linking done")
+		setCacheStatusHeader(w, scope, opts.ResourceVersion)
 		write(http.StatusOK, scope.Kind.GroupVersion(), scope.Codec, result, w, req.Request)
 		trace.Step(fmt.Sprintf("Writing http response done (%d items)", numberOfItems))
 	}
@@ -381,17 +460,10 @@ func createHandler(r rest.NamedCreater, scope RequestScope, typer runtime.Object
 		ctx := scope.ContextFunc(req)
 		ctx = api.WithNamespace(ctx, namespace)
 
-		body, err := readBody(req.Request)
-		if err != nil {
-			errorJSON(err, scope.Codec, w)
-			return
-		}
-
 		obj := r.New()
 		trace.Step("About to convert to expected version")
 		// TODO this cleans up with proper typing
-		if err := scope.Codec.DecodeIntoWithSpecifiedVersionKind(body, obj, scope.Kind); err != nil {
-			err = transformDecodeError(typer, err, obj, body)
+		if err := decodeRequestBody(req.Request, &scope, typer, obj); err != nil {
 			errorJSON(err, scope.Codec, w)
 			return
 		}
@@ -624,16 +696,9 @@ func UpdateResource(r rest.Updater, scope RequestScope, typer runtime.ObjectType
 		ctx := scope.ContextFunc(req)
 		ctx = api.WithNamespace(ctx, namespace)
 
-		body, err := readBody(req.Request)
-		if err != nil {
-			errorJSON(err, scope.Codec, w)
-			return
-		}
-
 		obj := r.New()
 		trace.Step("About to convert to expected version")
-		if err := scope.Codec.DecodeIntoWithSpecifiedVersionKind(body, obj, scope.Kind); err != nil {
-			err = transformDecodeError(typer, err, obj, body)
+		if err := decodeRequestBody(req.Request, &scope, typer, obj); err != nil {
 			errorJSON(err, scope.Codec, w)
 			return
 		}
@@ -903,6 +968,29 @@ func finishRequest(timeout time.Duration, fn resultFunc) (result runtime.Object,
 	}
 }
 
+// decodeRequestBody decodes the request body into obj. When the scope enables
+// streaming decode and the codec supports it, the object is decoded directly
+// from the body stream so large requests are never fully buffered; otherwise
+// the body is read into memory and decoded as before.
+func decodeRequestBody(req *http.Request, scope *RequestScope, typer runtime.ObjectTyper, obj runtime.Object) error {
+	if scope.StreamingDecode {
+		if streamer, ok := scope.Codec.(runtime.StreamingDecoder); ok {
+			if err := streamer.DecodeFromStreamInto(req.Body, obj, scope.Kind); err != nil {
+				return transformDecodeError(typer, err, obj, nil)
+			}
+			return nil
+		}
+	}
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	if err := scope.Codec.DecodeIntoWithSpecifiedVersionKind(body, obj, scope.Kind); err != nil {
+		return transformDecodeError(typer, err, obj, body)
+	}
+	return nil
+}
+
 // transformDecodeError adds additional information when a decode fails.
 func transformDecodeError(typer runtime.ObjectTyper, baseErr error, into runtime.Object, body []byte) error {
 	objectGroupVersionKind, err := typer.ObjectKind(into)
@@ -19,6 +19,7 @@ package apiserver
 import (
 	"errors"
 	"fmt"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 	"time"
@@ -329,3 +330,45 @@ func TestPatchResourceWithConflict(t *testing.T) {
 
 	tc.Run(t)
 }
+
+func TestSetCacheStatusHeader(t *testing.T) {
+	testCases := []struct {
+		cacheEnabled    bool
+		resourceVersion string
+		expected        string
+	}{
+		{false, "0", ""},
+		{false, "", ""},
+		{true, "0", "hit"},
+		{true, "", "miss"},
+		{true, "1234", "bypass"},
+	}
+	for i, tc := range testCases {
+		w := httptest.NewRecorder()
+		setCacheStatusHeader(w, RequestScope{CacheEnabled: tc.cacheEnabled}, tc.resourceVersion)
+		if actual := w.Header().Get("X-Cache-Status"); actual != tc.expected {
+			t.Errorf("%d: expected X-Cache-Status %q, got %q", i, tc.expected, actual)
+		}
+	}
+}
+
+func TestJitteredTimeout(t *testing.T) {
+	min := 30 * time.Minute
+	lowerHalf, upperHalf := 0, 0
+	for i := 0; i < 1000; i++ {
+		timeout := jitteredTimeout(min)
+		if timeout < min || timeout >= 2*min {
+			t.Fatalf("timeout %v outside [%v, %v)", timeout, min, 2*min)
+		}
+		if timeout < min+min/2 {
+			lowerHalf++
+		} else {
+			upperHalf++
+		}
+	}
+	// The jitter should actually spread timeouts across the interval rather
+	// than cluster at one end.
+	if lowerHalf == 0 || upperHalf == 0 {
+		t.Errorf("expected timeouts in both halves of the interval, got %d/%d", lowerHalf, upperHalf)
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"regexp"
+)
+
+// UnknownUserAgent is the bucket assigned to user agents that match none of
+// the configured patterns.
+const UnknownUserAgent = "other"
+
+// UserAgentPattern maps user agents matching a regular expression to a stable
+// component name. Raw user agents vary per build (e.g. every kubectl version
+// reports a distinct string), which explodes metric cardinality when used as
+// a label directly.
+type UserAgentPattern struct {
+	// Regexp is matched against the request's User-Agent header.
+	Regexp string
+	// Component is the name reported for matching user agents.
+	Component string
+}
+
+// UserAgentNormalizer maps raw user agents to a bounded set of component
+// names, to be used when labeling metrics or log entries.
+type UserAgentNormalizer struct {
+	regexps    []*regexp.Regexp
+	components []string
+}
+
+// NewUserAgentNormalizer compiles the given patterns. Patterns are evaluated
+// in order; the first match wins.
+func NewUserAgentNormalizer(patterns []UserAgentPattern) (*UserAgentNormalizer, error) {
+	n := &UserAgentNormalizer{}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern.Regexp)
+		if err != nil {
+			return nil, err
+		}
+		n.regexps = append(n.regexps, re)
+		n.components = append(n.components, pattern.Component)
+	}
+	return n, nil
+}
+
+// Normalize returns the component name of the first pattern matching the
+// given user agent, or UnknownUserAgent when none match.
+func (n *UserAgentNormalizer) Normalize(userAgent string) string {
+	for i, re := range n.regexps {
+		if re.MatchString(userAgent) {
+			return n.components[i]
+		}
+	}
+	return UnknownUserAgent
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"testing"
+)
+
+func TestUserAgentNormalizer(t *testing.T) {
+	normalizer, err := NewUserAgentNormalizer([]UserAgentPattern{
+		{Regexp: "^kubectl/", Component: "kubectl"},
+		{Regexp: "kube-controller-manager", Component: "kube-controller-manager"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testCases := map[string]string{
+		"kubectl/v1.1.0 (linux/amd64)":            "kubectl",
+		"kubectl/v1.0.6 (darwin/amd64)":           "kubectl",
+		"kube-controller-manager/v1.1.0":          "kube-controller-manager",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X)": UnknownUserAgent,
+		"": UnknownUserAgent,
+	}
+	for userAgent, expected := range testCases {
+		if actual := normalizer.Normalize(userAgent); actual != expected {
+			t.Errorf("%q: expected %q, got %q", userAgent, expected, actual)
+		}
+	}
+
+	if _, err := NewUserAgentNormalizer([]UserAgentPattern{{Regexp: "(", Component: "bad"}}); err == nil {
+		t.Errorf("expected error for invalid pattern")
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genericapiserver
+
+import (
+	"k8s.io/kubernetes/pkg/admission"
+	apierrors "k8s.io/kubernetes/pkg/api/errors"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+)
+
+// limitedAdmission bounds the number of concurrent calls into the admission
+// chain, protecting external admission backends (such as webhooks) from
+// being overwhelmed by a flood of mutating requests. Calls beyond the limit
+// are rejected with a 429 asking the client to retry rather than queued
+// indefinitely.
+type limitedAdmission struct {
+	delegate admission.Interface
+	sem      chan struct{}
+}
+
+func newLimitedAdmission(delegate admission.Interface, limit int) admission.Interface {
+	return &limitedAdmission{
+		delegate: delegate,
+		sem:      make(chan struct{}, limit),
+	}
+}
+
+func (l *limitedAdmission) Admit(a admission.Attributes) error {
+	select {
+	case l.sem <- struct{}{}:
+		defer func() { <-l.sem }()
+		return l.delegate.Admit(a)
+	default:
+		return &apierrors.StatusError{ErrStatus: unversioned.Status{
+			Status:  unversioned.StatusFailure,
+			Code:    apierrors.StatusTooManyRequests,
+			Reason:  unversioned.StatusReasonTimeout,
+			Message: "Too many concurrent admission requests, please try again later.",
+			Details: &unversioned.StatusDetails{
+				RetryAfterSeconds: 1,
+			},
+		}}
+	}
+}
+
+func (l *limitedAdmission) Handles(operation admission.Operation) bool {
+	return l.delegate.Handles(operation)
+}
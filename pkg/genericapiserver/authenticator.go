@@ -0,0 +1,77 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genericapiserver
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"k8s.io/kubernetes/pkg/auth/authenticator"
+	"k8s.io/kubernetes/pkg/auth/user"
+	"k8s.io/kubernetes/plugin/pkg/auth/authenticator/request/union"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var authenticationAttempts = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "apiserver_authentication_attempts",
+		Help: "Counter of authentication attempts broken out by authenticator and result.",
+	},
+	[]string{"authenticator", "result"},
+)
+
+var registerAuthenticationMetric sync.Once
+
+// NewUnionAuthenticator returns a request authenticator that tries each of
+// the given authenticators in order and returns the first success, like the
+// union plugin, but additionally counts per-authenticator successes and
+// failures so operators can see which auth methods their clients actually
+// use. Authenticators are identified in the metric by their Go type.
+func NewUnionAuthenticator(authenticators ...authenticator.Request) authenticator.Request {
+	registerAuthenticationMetric.Do(func() {
+		prometheus.MustRegister(authenticationAttempts)
+	})
+	instrumented := make([]authenticator.Request, 0, len(authenticators))
+	for _, a := range authenticators {
+		instrumented = append(instrumented, &instrumentedAuthenticator{name: fmt.Sprintf("%T", a), delegate: a})
+	}
+	return union.New(instrumented...)
+}
+
+// instrumentedAuthenticator counts the outcome of every authentication
+// attempt against its delegate.
+type instrumentedAuthenticator struct {
+	name     string
+	delegate authenticator.Request
+}
+
+func (a *instrumentedAuthenticator) AuthenticateRequest(req *http.Request) (user.Info, bool, error) {
+	info, ok, err := a.delegate.AuthenticateRequest(req)
+	switch {
+	case err == nil && ok:
+		authenticationAttempts.WithLabelValues(a.name, "success").Inc()
+	case err != nil:
+		authenticationAttempts.WithLabelValues(a.name, "error").Inc()
+	default:
+		// The request carried no credentials this authenticator understands;
+		// the union moves on to the next one.
+		authenticationAttempts.WithLabelValues(a.name, "miss").Inc()
+	}
+	return info, ok, err
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genericapiserver
+
+import (
+	"time"
+
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/storage"
+	"k8s.io/kubernetes/pkg/util"
+	utilerrors "k8s.io/kubernetes/pkg/util/errors"
+
+	"github.com/golang/glog"
+	"golang.org/x/net/context"
+)
+
+// compactionLockKey is the storage key used to elect the master that triggers
+// compaction for a given interval.
+const compactionLockKey = "/compactionlock"
+
+// Compactor triggers a compaction of an underlying storage backend. The
+// storage layer provides the implementation; the generic server only
+// coordinates when it runs.
+type Compactor interface {
+	Compact() error
+}
+
+// storageCompactor adapts the configured storage destinations to the
+// Compactor interface: every distinct backend whose storage implements
+// Compactor is compacted in turn, and backends without compaction support
+// are skipped.
+type storageCompactor struct {
+	destinations StorageDestinations
+}
+
+func (c *storageCompactor) Compact() error {
+	var errlist []error
+	for _, store := range c.destinations.AllStores() {
+		compactor, ok := store.(Compactor)
+		if !ok {
+			continue
+		}
+		if err := compactor.Compact(); err != nil {
+			errlist = append(errlist, err)
+		}
+	}
+	return utilerrors.NewAggregate(errlist)
+}
+
+// compactionCoordinator ensures that in a multi-master deployment only one
+// master triggers storage compaction per interval. Coordination is done with
+// a TTL-scoped lock object in storage: whichever master creates the lock for
+// the current interval runs the compaction, the others skip it.
+type compactionCoordinator struct {
+	storage   storage.Interface
+	compactor Compactor
+	interval  time.Duration
+}
+
+// Run triggers coordinated compaction every interval until stopCh is closed.
+func (c *compactionCoordinator) Run(stopCh <-chan struct{}) {
+	util.Until(c.runOnce, c.interval, stopCh)
+}
+
+func (c *compactionCoordinator) runOnce() {
+	// The lock expires together with the interval, so a crashed lock holder
+	// only delays the next compaction by a single round.
+	ttl := uint64(c.interval / time.Second)
+	if ttl == 0 {
+		ttl = 1
+	}
+	lock := &api.Endpoints{ObjectMeta: api.ObjectMeta{Name: "compaction"}}
+	out := &api.Endpoints{}
+	if err := c.storage.Create(context.TODO(), compactionLockKey, lock, out, ttl); err != nil {
+		// Another master holds the lock for this interval.
+		glog.V(4).Infof("Not triggering storage compaction: %v", err)
+		return
+	}
+	glog.V(2).Infof("Triggering storage compaction")
+	if err := c.compactor.Compact(); err != nil {
+		glog.Errorf("Storage compaction failed: %v", err)
+	}
+}
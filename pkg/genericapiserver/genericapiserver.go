@@ -18,14 +18,25 @@ package genericapiserver
 
 import (
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
 	"net"
 	"net/http"
 	"net/http/pprof"
+	"net/url"
+	"os"
 	"path"
 	"regexp"
+	goruntime "runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"k8s.io/kubernetes/pkg/admission"
@@ -34,12 +45,15 @@ import (
 	"k8s.io/kubernetes/pkg/api/rest"
 	"k8s.io/kubernetes/pkg/api/unversioned"
 	"k8s.io/kubernetes/pkg/apiserver"
+	"k8s.io/kubernetes/pkg/apiserver/metrics"
 	"k8s.io/kubernetes/pkg/auth/authenticator"
 	"k8s.io/kubernetes/pkg/auth/authorizer"
 	"k8s.io/kubernetes/pkg/auth/handlers"
+	"k8s.io/kubernetes/pkg/healthz"
 	"k8s.io/kubernetes/pkg/registry/generic"
 	genericetcd "k8s.io/kubernetes/pkg/registry/generic/etcd"
 	ipallocator "k8s.io/kubernetes/pkg/registry/service/ipallocator"
+	"k8s.io/kubernetes/pkg/runtime"
 	"k8s.io/kubernetes/pkg/storage"
 	"k8s.io/kubernetes/pkg/ui"
 	"k8s.io/kubernetes/pkg/util"
@@ -49,22 +63,58 @@ import (
 	"github.com/emicklei/go-restful"
 	"github.com/emicklei/go-restful/swagger"
 	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/net/context"
 )
 
 const (
 	DefaultEtcdPathPrefix = "/registry"
+
+	// maxSecureServeRetryInterval caps the exponential backoff between
+	// attempts to bind the secure port; maxSecureServeFailures is the number
+	// of consecutive failures after which the server gives up and exits.
+	maxSecureServeRetryInterval = 5 * time.Minute
+	maxSecureServeFailures      = 8
+)
+
+var storageFallbackCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "apiserver_storage_fallback_count",
+		Help: "Counter of storage lookups served by the global fallback destination, per requested API group.",
+	},
+	[]string{"group"},
 )
 
+var registerStorageFallbackMetric sync.Once
+
 // StorageDestinations is a mapping from API group & resource to
 // the underlying storage interfaces.
 type StorageDestinations struct {
 	APIGroups map[string]*StorageDestinationsForAPIGroup
+
+	// Fallback, if set, serves lookups for groups without a registered
+	// destination, so aggregated servers can route unknown groups to a
+	// generic backend. When unset, lookups for unknown groups return nil as
+	// before.
+	Fallback storage.Interface
 }
 
 type StorageDestinationsForAPIGroup struct {
 	Default   storage.Interface
 	Overrides map[string]storage.Interface
+
+	// ReadOverrides directs read traffic (GET/LIST) for individual resources
+	// to a separate backend, e.g. etcd followers, while mutations keep using
+	// Default or Overrides.
+	ReadOverrides map[string]storage.Interface
+
+	// PathPrefix records the etcd key root the group's default storage writes
+	// under, when it differs from the global prefix. Storing a group under
+	// its own root lets it be snapshotted and restored independently. The
+	// prefix itself is baked into Default at construction time; decorated
+	// storage (watch caches etc.) delegates key handling to Default and so
+	// uses it as well. Empty means the global prefix.
+	PathPrefix string
 }
 
 func NewStorageDestinations() StorageDestinations {
@@ -74,9 +124,19 @@ func NewStorageDestinations() StorageDestinations {
 }
 
 func (s *StorageDestinations) AddAPIGroup(group string, defaultStorage storage.Interface) {
+	s.AddAPIGroupWithPrefix(group, defaultStorage, "")
+}
+
+// AddAPIGroupWithPrefix registers a group whose storage writes under its own
+// etcd key root instead of the global prefix. The caller must construct
+// defaultStorage with the given prefix; it is recorded here so diagnostics
+// can report which root each group uses. Backends() reports the backends of
+// every group regardless of prefix.
+func (s *StorageDestinations) AddAPIGroupWithPrefix(group string, defaultStorage storage.Interface, pathPrefix string) {
 	s.APIGroups[group] = &StorageDestinationsForAPIGroup{
-		Default:   defaultStorage,
-		Overrides: map[string]storage.Interface{},
+		Default:    defaultStorage,
+		Overrides:  map[string]storage.Interface{},
+		PathPrefix: pathPrefix,
 	}
 }
 
@@ -90,9 +150,54 @@ func (s *StorageDestinations) AddStorageOverride(group, resource string, overrid
 	s.APIGroups[group].Overrides[resource] = override
 }
 
+// AddReadStorageOverride directs read traffic for a resource to a separate
+// backend; writes are unaffected.
+func (s *StorageDestinations) AddReadStorageOverride(group, resource string, override storage.Interface) {
+	if _, ok := s.APIGroups[group]; !ok {
+		s.AddAPIGroup(group, nil)
+	}
+	if s.APIGroups[group].ReadOverrides == nil {
+		s.APIGroups[group].ReadOverrides = map[string]storage.Interface{}
+	}
+	s.APIGroups[group].ReadOverrides[resource] = override
+}
+
+// SetFallback configures a global fallback storage used when a lookup matches
+// no registered group.
+func (s *StorageDestinations) SetFallback(fallback storage.Interface) {
+	registerStorageFallbackMetric.Do(func() {
+		prometheus.MustRegister(storageFallbackCounter)
+	})
+	s.Fallback = fallback
+}
+
+// Operation hints for GetForOperation.
+const (
+	StorageOperationRead  = "read"
+	StorageOperationWrite = "write"
+)
+
+// GetForOperation returns the storage destination for a group and resource,
+// directing reads to a registered read override when one exists. Without a
+// read override it behaves exactly like Get.
+func (s *StorageDestinations) GetForOperation(group, resource, operation string) storage.Interface {
+	if operation == StorageOperationRead {
+		if apigroup, ok := s.APIGroups[group]; ok && apigroup.ReadOverrides != nil {
+			if client, exists := apigroup.ReadOverrides[resource]; exists {
+				return client
+			}
+		}
+	}
+	return s.Get(group, resource)
+}
+
 func (s *StorageDestinations) Get(group, resource string) storage.Interface {
 	apigroup, ok := s.APIGroups[group]
 	if !ok {
+		if s.Fallback != nil {
+			storageFallbackCounter.WithLabelValues(group).Inc()
+			return s.Fallback
+		}
 		glog.Errorf("No storage defined for API group: '%s'", apigroup)
 		return nil
 	}
@@ -121,10 +226,48 @@ func (s *StorageDestinations) Backends() []string {
 				}
 			}
 		}
+		if group.ReadOverrides != nil {
+			for _, storage := range group.ReadOverrides {
+				for _, backend := range storage.Backends(context.TODO()) {
+					backends.Insert(backend)
+				}
+			}
+		}
+	}
+	if s.Fallback != nil {
+		for _, backend := range s.Fallback.Backends(context.TODO()) {
+			backends.Insert(backend)
+		}
 	}
 	return backends.List()
 }
 
+// AllStores returns every distinct storage interface registered across all
+// destinations, including overrides and the fallback. Used for operations
+// that must touch every backend, such as coordinated compaction.
+func (s *StorageDestinations) AllStores() []storage.Interface {
+	stores := []storage.Interface{}
+	seen := map[storage.Interface]bool{}
+	add := func(store storage.Interface) {
+		if store == nil || seen[store] {
+			return
+		}
+		seen[store] = true
+		stores = append(stores, store)
+	}
+	for _, group := range s.APIGroups {
+		add(group.Default)
+		for _, store := range group.Overrides {
+			add(store)
+		}
+		for _, store := range group.ReadOverrides {
+			add(store)
+		}
+	}
+	add(s.Fallback)
+	return stores
+}
+
 // Specifies the overrides for various API group versions.
 // This can be used to enable/disable entire group versions or specific resources.
 type APIGroupVersionOverride struct {
@@ -147,6 +290,18 @@ type APIGroupInfo struct {
 	// If nil, defaults to groupMeta.GroupVersion.
 	// TODO: Remove this when https://github.com/kubernetes/kubernetes/issues/19018 is fixed.
 	OptionsExternalVersion *unversioned.GroupVersion
+	// DeprecatedVersions maps version names in this group to deprecation
+	// metadata. Responses served by those versions carry Deprecation and Link
+	// headers so clients get a machine-readable migration signal.
+	DeprecatedVersions map[string]apiserver.VersionDeprecation
+	// DependsOn lists names of groups that must be installed before this one,
+	// e.g. a group whose admission control needs another group's storage.
+	// InstallAPIGroups orders installation to satisfy the declared
+	// dependencies and errors on cycles or missing dependencies.
+	DependsOn []string
+	// AdmissionControl, if set, replaces the server-wide admission chain for
+	// this group's versions. When nil the server default is used.
+	AdmissionControl admission.Interface
 }
 
 // Config is a structure used to configure a GenericAPIServer.
@@ -159,25 +314,225 @@ type Config struct {
 	EnableUISupport   bool
 	// allow downstream consumers to disable swagger
 	EnableSwaggerSupport bool
+	// SwaggerWebServicesURL is the fully-qualified base URL (scheme and host)
+	// advertised in the swagger spec. When unset the URL is guessed from the
+	// external host or cluster IP, which is wrong behind a TLS-terminating
+	// proxy where the external scheme and host differ from the server's own.
+	SwaggerWebServicesURL string
 	// Allows api group versions or specific resources to be conditionally enabled/disabled.
 	APIGroupVersionOverrides map[string]APIGroupVersionOverride
 	// allow downstream consumers to disable the index route
 	EnableIndex           bool
 	EnableProfiling       bool
-	EnableWatchCache      bool
+	// EnableMetrics serves Prometheus metrics on /metrics and instruments the
+	// handler chain with request counts, latencies, and an in-flight gauge.
+	EnableMetrics    bool
+	EnableWatchCache bool
+	// WatchCacheSizes overrides the watch cache capacity per resource (by
+	// resource prefix, e.g. "pods"); WatchCacheSize, when non-zero, replaces
+	// the capacity for every resource not listed. Resources covered by
+	// neither keep the capacity their registry requested.
+	WatchCacheSizes map[string]int
+	WatchCacheSize  int
+	// Reject requests with ambiguous framing headers (duplicate
+	// Content-Length, or Content-Length combined with Transfer-Encoding)
+	// before they reach any handler, to close request-smuggling vectors when
+	// running behind certain proxies.
+	RejectAmbiguousFraming bool
+	// ForceQuorumReads lists resources (by resource prefix, e.g. "secrets")
+	// that must always be read directly from etcd, bypassing the watch cache,
+	// even when EnableWatchCache is true. Use this for security-sensitive
+	// resources where a stale read is not acceptable.
+	ForceQuorumReads map[string]bool
 	APIPrefix             string
 	APIGroupPrefix        string
 	CorsAllowedOriginList []string
-	Authenticator         authenticator.Request
+	// CorsAllowedMethods and CorsAllowedHeaders restrict what the CORS filter
+	// advertises; empty keeps the filter's permissive defaults. CorsMaxAge,
+	// when non-zero, sets Access-Control-Max-Age so browsers cache preflight
+	// results instead of re-sending OPTIONS before every request.
+	CorsAllowedMethods []string
+	CorsAllowedHeaders []string
+	CorsMaxAge         time.Duration
+	Authenticator      authenticator.Request
+	// UnauthorizedHandler, if set, writes the response for requests that fail
+	// authentication, e.g. to emit a JSON body or a WWW-Authenticate header
+	// pointing at an external issuer. Nil keeps the default fixed 401.
+	UnauthorizedHandler http.Handler
 	// TODO(roberthbailey): Remove once the server no longer supports http basic auth.
 	SupportsBasicAuth      bool
 	Authorizer             authorizer.Authorizer
 	AdmissionControl       admission.Interface
 	MasterServiceNamespace string
 
+	// AllowedQueryParams restricts, per verb, which query parameters are
+	// accepted; requests with other parameters are rejected with a 400.
+	// Verbs without an entry remain unrestricted, so leaving this empty
+	// preserves the current permissive behavior.
+	AllowedQueryParams map[string][]string
+
+	// ConnectionKeepAlivePeriod enables liveness probing of idle secure
+	// connections, so connections silently dropped by NATs or load balancers
+	// are detected and closed promptly instead of leaving dead watches.
+	// The vendored net libraries predate HTTP/2 server pings, so this is
+	// applied as a TCP keepalive period on the secure listener. Zero keeps
+	// the operating system default.
+	ConnectionKeepAlivePeriod time.Duration
+
+	// ReadOnlyPort, if non-zero, serves the insecure handler on an additional
+	// listener on which only read requests are allowed; mutations are
+	// rejected with a 403. Intended for internal monitoring networks.
+	ReadOnlyPort int
+
+	// JSONNumberAsString emits integers whose magnitude exceeds what an IEEE
+	// 754 double can represent exactly as JSON strings, so JavaScript clients
+	// do not silently corrupt large IDs. JSONTimestampFormat, when set,
+	// re-renders RFC3339 timestamps in the given time layout. Both options
+	// leave decoding unchanged.
+	JSONNumberAsString  bool
+	JSONTimestampFormat string
+
+	// MaxConnectionsPerIP caps concurrent connections per source IP on both
+	// listeners, so one misbehaving client cannot exhaust the server's
+	// connection capacity for everyone. Connections over the limit are closed
+	// on accept and counted in a rejection metric. Zero disables the limit.
+	MaxConnectionsPerIP int
+
+	// RequireContentTypeOnWrites rejects mutating requests that carry a body
+	// but no Content-Type header with a 415 instead of guessing the content
+	// type. DefaultRequestContentType, when set (and strict mode is off),
+	// fills in the missing header instead. With neither set, the current
+	// guessing behavior is preserved.
+	RequireContentTypeOnWrites bool
+	DefaultRequestContentType  string
+
+	// EnableCompression gzips responses for clients that advertise gzip
+	// support. Watch and other long-running responses are always served
+	// uncompressed so event delivery is never delayed by compressor
+	// buffering.
+	EnableCompression bool
+
+	// DefaultListLimit bounds LIST responses when the client does not supply
+	// a limit query parameter, injecting a continue token so clients
+	// paginate by default. An explicit limit=0 opts out. Zero disables the
+	// default. ListLimitOverrides adjusts the default for individual
+	// resources (by resource name).
+	DefaultListLimit   int
+	ListLimitOverrides map[string]int
+
+	// EmitFairnessHeaders reports in response headers which priority lane
+	// and limit bucket each request was assigned to, so throttling decisions
+	// are debuggable from the client side.
+	EmitFairnessHeaders bool
+
+	// EmitTimeoutHeader reports the effective server-side timeout of each
+	// request in an X-Timeout-Seconds response header — the randomized
+	// long-running timeout for watches, or the fixed request timeout
+	// otherwise — so clients can choose a compatible client-side timeout.
+	EmitTimeoutHeader bool
+
+	// StreamingDecode decodes request bodies directly from the stream for
+	// codecs that support it, reducing peak memory for large object writes.
+	// Off by default since not all serializers decode safely from a stream.
+	StreamingDecode bool
+
+	// LatencyBuckets overrides the buckets (in microseconds) of the request
+	// latency histogram, so operators can align metrics with their SLO
+	// thresholds. Empty keeps the defaults.
+	LatencyBuckets []float64
+
+	// EnableTLSNegotiationMetrics records the negotiated TLS version and
+	// cipher suite of each secure request as a metric labeled by both, so
+	// operators can see what clients actually negotiate before tightening
+	// the server's TLS requirements.
+	EnableTLSNegotiationMetrics bool
+
+	// TLSNextProtos lists the ALPN protocols advertised by the secure server,
+	// in preference order, for deployments multiplexing gRPC or other custom
+	// protocols over the serving port. The list must include "http/1.1" so
+	// ordinary API clients keep working. Empty means HTTP/1.1 only.
+	TLSNextProtos []string
+
+	// RequireTLSForMutations rejects mutating verbs arriving on the insecure
+	// handler with a 403 directing clients to the secure endpoint, while
+	// still allowing reads. The secure handler is unaffected.
+	RequireTLSForMutations bool
+
+	// RejectHTTP10Watches rejects watch requests from HTTP/1.0 clients with
+	// a clear 400 instead of letting the connection hang; HTTP/1.0 cannot
+	// carry a streaming response. Simple requests are unaffected.
+	RejectHTTP10Watches bool
+
+	// PathRewriteRules rewrites incoming request paths before routing and
+	// authorization, easing API group-version migrations while old clients
+	// catch up. Rewritten requests receive a deprecation warning header.
+	PathRewriteRules []apiserver.PathRewrite
+
+	// ChaosLatencyConfig injects artificial latency into a fraction of
+	// requests for chaos testing. Off by default; activation requires the
+	// config's explicit UnsafeAllowChaos flag so it cannot be enabled
+	// accidentally in production.
+	ChaosLatencyConfig *apiserver.ChaosLatencyConfig
+
+	// ClientCertExpiryGrace rejects requests authenticated with a client
+	// certificate that expires within the given duration, forcing rotation
+	// before the certificate becomes unusable. Zero disables rejection.
+	ClientCertExpiryGrace time.Duration
+	// ClientCertExpiryWarning emits a "Warning: 299" response header when the
+	// client certificate expires within the given duration. Zero disables
+	// the warning.
+	ClientCertExpiryWarning time.Duration
+
+	// CertAttributeExtractor, if set, derives attributes (OU, custom
+	// extensions) from the verified client certificate after the TLS
+	// handshake; the attributes are stored in the request context for
+	// downstream authorization and audit.
+	CertAttributeExtractor apiserver.CertAttributeExtractor
+
+	// MaxWatchesPerUser caps the number of watch requests a single
+	// authenticated user may hold open at once; excess watches are rejected
+	// with 429. Watches are exempt from the in-flight limit, so this is the
+	// only backpressure on runaway watch clients. Zero disables the cap.
+	MaxWatchesPerUser int
+
+	// BuildHandlerChain, if set, replaces DefaultBuildHandlerChain for
+	// wrapping the API handler with the per-request filters (authorization,
+	// authentication, request IDs, ...). Embedders needing to insert filters
+	// at specific points can call DefaultBuildHandlerChain from their own
+	// function instead of forking init. Filters applied to both the secure
+	// and insecure handlers (audit, framing checks, maintenance mode, the
+	// context filter, ...) are added outside the chain and are not affected.
+	BuildHandlerChain func(apiHandler http.Handler, c *Config) http.Handler
+
+	// RequestIDHeader, if set, names a header carrying a correlation ID.
+	// Requests without one get a generated UUID; the ID is stored in the
+	// request context and echoed in the response, so logs can be correlated
+	// across services. Empty disables the handling.
+	RequestIDHeader string
+
+	// Called with the request attributes and the authorizer's reason for
+	// every denied authorization. Defaults to logging the denial. Denials
+	// are always audited, regardless of any per-resource audit policy.
+	DeniedAuthorizationAudit apiserver.DeniedAuthorizationAuditFunc
+
+	// AuditWriter, when set, receives one line per completed request with
+	// the authenticated user, verb, resource, namespace, source IP and
+	// response code. Lines are written after the response, so the status
+	// code is the one actually returned.
+	AuditWriter io.Writer
+
 	// Map requests to contexts. Exported so downstream consumers can provider their own mappers
 	RequestContextMapper api.RequestContextMapper
 
+	// If specified, called with a request's final context when the request
+	// completes, so per-request resources stored in the context can be
+	// released.
+	RequestContextCleaner api.RequestContextCleaner
+
+	// UserAgentPatterns maps user agents to a bounded set of component names
+	// for metrics and log labeling. Unmatched agents are bucketed as "other".
+	UserAgentPatterns []apiserver.UserAgentPattern
+
 	// If specified, all web services will be registered into this container
 	RestfulContainer *restful.Container
 
@@ -189,6 +544,68 @@ type Config struct {
 	// same value for this field. (Numbers > 1 currently untested.)
 	MasterCount int
 
+	// CompactionInterval controls how often storage compaction is triggered.
+	// In multi-master deployments a lock in storage ensures only one master
+	// triggers compaction per interval. Zero disables coordinated compaction.
+	CompactionInterval time.Duration
+	// Compactor performs the storage compaction when this master wins the
+	// compaction lock. Required when CompactionInterval is non-zero.
+	Compactor Compactor
+	// EtcdCompactionInterval triggers coordinated compaction of the storage
+	// backends themselves every interval when MasterCount > 1, so only one
+	// master compacts at a time. Backends that do not implement Compactor
+	// are skipped. Zero disables it; an explicitly configured Compactor via
+	// CompactionInterval takes precedence.
+	EtcdCompactionInterval time.Duration
+
+	// RelistExpiredWatches makes the watch cache resume watches whose
+	// resourceVersion has fallen out of the cache window from its current
+	// state instead of failing them with 410 Gone, for clients that do not
+	// handle watch expiry gracefully.
+	RelistExpiredWatches bool
+
+	// MaxConcurrentAdmission bounds concurrent calls into the admission
+	// chain; calls beyond the limit are rejected with a 429. This protects
+	// external admission backends from being overwhelmed by the apiserver
+	// itself. Zero leaves admission unbounded.
+	MaxConcurrentAdmission int
+
+	// AllowedHosts restricts which Host headers the server answers, closing
+	// DNS rebinding and host-header manipulation vectors behind misconfigured
+	// proxies. ExternalHost and the public address are always allowed. Empty
+	// allows all hosts, preserving current behavior.
+	AllowedHosts []string
+
+	// HealthzBackendTimeout bounds each backend ping performed by the
+	// /healthz/etcd endpoint. Defaults to 2 seconds.
+	HealthzBackendTimeout time.Duration
+
+	// ReadinessCacheTTL is how long /readyz reuses the last storage backend
+	// health result before probing the backends again, so frequent readiness
+	// probes do not each ping etcd. Defaults to 5 seconds.
+	ReadinessCacheTTL time.Duration
+
+	// WaitForStorageAtStartup makes Run block until every backend in
+	// StorageDestinations.Backends() is reachable before serving, so a load
+	// balancer never routes to an apiserver that cannot reach its storage.
+	// StorageWaitTimeout bounds the wait (defaulting to one minute); if it
+	// expires with backends still unreachable, startup fails.
+	WaitForStorageAtStartup bool
+	StorageWaitTimeout      time.Duration
+
+	// IdentityLeaseTTL, if non-zero, publishes a heartbeat lease in storage
+	// identifying this apiserver instance, renewed continuously and removed
+	// on graceful shutdown, so HA tooling can enumerate live instances.
+	IdentityLeaseTTL time.Duration
+
+	// EnableWarmStandby starts the server as a warm standby: reads are
+	// served, but writes are rejected with a leader hint and OnActive
+	// callbacks are deferred until this instance acquires the leader lease.
+	// StandbyLeaseTTL bounds how long a crashed leader blocks failover;
+	// zero keeps the default.
+	EnableWarmStandby bool
+	StandbyLeaseTTL   time.Duration
+
 	// The port on PublicAddress where a read-write server will be installed.
 	// Defaults to 6443 if not set.
 	ReadWritePort int
@@ -217,6 +634,10 @@ type Config struct {
 	// Used to customize default proxy dial/tls options
 	ProxyDialer          apiserver.ProxyDialerFunc
 	ProxyTLSClientConfig *tls.Config
+	// ProxyTransport, when non-nil, is used as-is for proxied requests and
+	// bypasses the transport built from ProxyDialer and ProxyTLSClientConfig,
+	// so callers can inject an instrumented RoundTripper of their own.
+	ProxyTransport http.RoundTripper
 
 	// Additional ports to be exposed on the GenericAPIServer service
 	// extraServicePorts is injectable in the event that more ports
@@ -248,8 +669,14 @@ type GenericAPIServer struct {
 	enableLogsSupport        bool
 	enableUISupport          bool
 	enableSwaggerSupport     bool
+	swaggerWebServicesURL    string
 	enableProfiling          bool
+	enableMetrics            bool
 	enableWatchCache         bool
+	watchCacheSizes          map[string]int
+	defaultWatchCacheSize    int
+	relistExpiredWatches     bool
+	forceQuorumReads         map[string]bool
 	APIPrefix                string
 	APIGroupPrefix           string
 	corsAllowedOriginList    []string
@@ -271,6 +698,141 @@ type GenericAPIServer struct {
 	ExtraServicePorts    []api.ServicePort
 	ExtraEndpointPorts   []api.EndpointPort
 
+	// timeoutClamp shortens per-request timeouts once draining starts.
+	timeoutClamp apiserver.TimeoutClamp
+
+	// requestDrainer tracks in-flight requests by type so shutdown can wait
+	// for short requests before watches.
+	requestDrainer apiserver.RequestDrainer
+
+	// maintenanceMode short-circuits API requests with a 503 while toggled
+	// on, leaving operational endpoints alive.
+	maintenanceMode apiserver.MaintenanceMode
+
+	// connectionKeepAlivePeriod applies TCP keepalives on the secure listener.
+	connectionKeepAlivePeriod time.Duration
+
+	// tlsNextProtos is the ALPN protocol list advertised by the secure server.
+	tlsNextProtos []string
+
+	// enableTLSNegotiationMetrics records negotiated TLS parameters per request.
+	enableTLSNegotiationMetrics bool
+
+	// defaultListLimit and listLimitOverrides bound LIST responses when the
+	// client does not supply a limit.
+	defaultListLimit   int
+	listLimitOverrides map[string]int
+
+	// enableCompression gzips non-long-running responses for gzip clients.
+	enableCompression bool
+
+	// userAgentNormalizer maps user agents to component names for labeling.
+	userAgentNormalizer *apiserver.UserAgentNormalizer
+
+	// compactionCoordinator triggers coordinated storage compaction, if
+	// configured.
+	compactionCoordinator *compactionCoordinator
+
+	// instanceID uniquely identifies this apiserver process; when configured,
+	// identityLeaseController publishes it as a heartbeat lease in storage.
+	instanceID              string
+	identityLeaseController *identityLeaseController
+
+	// standbyController, when warm standby mode is enabled, elects the active
+	// instance and holds the current role.
+	standbyController *standbyController
+
+	// installedGroupVersions and storageBackends record what the server is
+	// actually serving, for the startup report.
+	installedGroupVersions []string
+	storageBackends        []string
+
+	// apiResourceMatrix records, per installed group and version, each
+	// resource and the verbs its storage supports, for /admin/apimatrix.
+	// The map is group -> version -> resource -> verbs; the legacy group is
+	// keyed by the empty string.
+	apiResourceMatrix map[string]map[string]map[string][]string
+
+	// runtimeGVLock guards the runtime enable/disable state of installed
+	// group versions.
+	runtimeGVLock sync.Mutex
+	// groupVersionWebServices maps installed group versions to their web
+	// services, so a version disabled at runtime can be installed again.
+	groupVersionWebServices map[string]*restful.WebService
+	// disabledGroupVersions tracks group versions removed at runtime via
+	// SetAPIGroupVersionEnabled.
+	disabledGroupVersions map[string]bool
+
+	// insecureUnixListener serves the insecure handler on a local Unix
+	// socket; the socket file is removed again on shutdown.
+	insecureUnixListener   net.Listener
+	insecureUnixSocketPath string
+
+	// secureListeners tracks the TLS listeners (one per secure bind address)
+	// so Drain can close them together; once closed, the serving goroutines
+	// stop instead of rebinding.
+	secureListenersLock   sync.Mutex
+	secureListeners       []net.Listener
+	secureListenersClosed bool
+
+	// postStartHooks run, in registration order, at the start of Run before
+	// the listeners are created.
+	postStartHooks []postStartHook
+
+	// serviceIPAllocator hands out cluster IPs from ServiceClusterIPRange,
+	// with the service read-write IP already reserved.
+	serviceIPAllocator *ipallocator.Range
+
+	// healthzChecks are served, in registration order, by the /healthz
+	// handler; the list may grow after the handler is installed.
+	healthzLock   sync.Mutex
+	healthzChecks []healthz.HealthzChecker
+
+	// storageDestinations backs the storage prefix diagnostic endpoint.
+	storageDestinations StorageDestinations
+
+	// healthzBackendTimeout bounds each backend ping in /healthz/etcd.
+	healthzBackendTimeout time.Duration
+
+	// readinessCacheTTL is how long /readyz reuses the last backend health
+	// result; the lock guards the cached result.
+	readinessCacheTTL time.Duration
+	readyzLock        sync.Mutex
+	lastReadyCheck    time.Time
+	lastReadyErr      error
+
+	// waitForStorageAtStartup gates serving on storage reachability.
+	waitForStorageAtStartup bool
+	storageWaitTimeout      time.Duration
+
+	// emitTimeoutHeader reports applied request timeouts to clients.
+	emitTimeoutHeader bool
+
+	// emitFairnessHeaders reports request lane and limit bucket to clients.
+	emitFairnessHeaders bool
+
+	// streamingDecode decodes request bodies directly from the stream for
+	// codecs that support it.
+	streamingDecode bool
+
+	// jsonNumberAsString and jsonTimestampFormat configure the JSON interop
+	// codec wrapper applied in newAPIGroupVersion.
+	jsonNumberAsString  bool
+	jsonTimestampFormat string
+
+	// readOnlyPort, if non-zero, serves the insecure handler read-only on an
+	// additional listener.
+	readOnlyPort int
+
+	// maxConnectionsPerIP caps concurrent connections per source IP on the
+	// listeners; zero disables the limit.
+	maxConnectionsPerIP int
+
+	// requestTimeoutFunc reports the timeout applied to a request (zero for
+	// long-running requests); Run installs it so the deadline can be
+	// propagated into request contexts.
+	requestTimeoutFunc func(req *http.Request) time.Duration
+
 	// storage contains the RESTful endpoints exposed by this GenericAPIServer
 	storage map[string]rest.Storage
 
@@ -284,35 +846,262 @@ type GenericAPIServer struct {
 	KubernetesServiceNodePort int
 }
 
+// NormalizeUserAgent maps the given user agent to one of the component names
+// configured via Config.UserAgentPatterns, or "other" when none match.
+func (s *GenericAPIServer) NormalizeUserAgent(userAgent string) string {
+	return s.userAgentNormalizer.Normalize(userAgent)
+}
+
+// StartDraining indicates that the server is shutting down and that in-flight
+// requests should complete within the given grace window. Per-request
+// timeouts handed out after this call are clamped to the remaining window so
+// a long-timeout request cannot block shutdown.
+func (s *GenericAPIServer) StartDraining(grace time.Duration) {
+	s.timeoutClamp.StartDraining(grace)
+}
+
+// Drain performs an ordered shutdown of in-flight traffic: new requests are
+// rejected, short requests are given shortGrace to finish, and watch and other
+// long-running connections are then given the full watchGrace window. Short
+// requests finish quickly, so draining them first leaves watches the maximum
+// time to disconnect cleanly.
+func (s *GenericAPIServer) Drain(shortGrace, watchGrace time.Duration) {
+	s.StartDraining(shortGrace + watchGrace)
+	s.requestDrainer.Drain(shortGrace, watchGrace)
+	if s.identityLeaseController != nil {
+		// Drop the identity lease immediately so tooling stops routing to
+		// this instance, rather than waiting for the lease TTL to expire.
+		s.identityLeaseController.remove()
+	}
+	if s.insecureUnixListener != nil {
+		// Stop accepting local connections and clean up the socket file.
+		s.insecureUnixListener.Close()
+		if err := os.Remove(s.insecureUnixSocketPath); err != nil && !os.IsNotExist(err) {
+			glog.Errorf("Unable to remove socket %s: %v", s.insecureUnixSocketPath, err)
+		}
+	}
+	// Close every secure listener so all secure bind addresses stop accepting
+	// together.
+	s.closeSecureListeners()
+}
+
+// trackSecureListener records a secure listener so Drain can close all of
+// them together. A listener registered after shutdown has begun is closed
+// immediately.
+func (s *GenericAPIServer) trackSecureListener(ln net.Listener) {
+	s.secureListenersLock.Lock()
+	defer s.secureListenersLock.Unlock()
+	if s.secureListenersClosed {
+		ln.Close()
+		return
+	}
+	s.secureListeners = append(s.secureListeners, ln)
+}
+
+// closeSecureListeners closes all tracked secure listeners and marks secure
+// serving stopped, so the serving goroutines exit instead of rebinding.
+func (s *GenericAPIServer) closeSecureListeners() {
+	s.secureListenersLock.Lock()
+	defer s.secureListenersLock.Unlock()
+	s.secureListenersClosed = true
+	for _, ln := range s.secureListeners {
+		ln.Close()
+	}
+	s.secureListeners = nil
+}
+
+// secureServingStopped reports whether secure serving has been shut down.
+func (s *GenericAPIServer) secureServingStopped() bool {
+	s.secureListenersLock.Lock()
+	defer s.secureListenersLock.Unlock()
+	return s.secureListenersClosed
+}
+
+// InstanceID returns the identifier unique to this apiserver process. When an
+// identity lease is configured, the lease is published under this ID.
+func (s *GenericAPIServer) InstanceID() string {
+	return s.instanceID
+}
+
+// StartupReport summarizes the server's effective configuration — enabled
+// features, installed API versions, TLS settings, limits and storage
+// backends — one human-readable line per item, so operators can verify what
+// the server is actually running with.
+func (s *GenericAPIServer) StartupReport() []string {
+	return []string{
+		fmt.Sprintf("instance: %s", s.instanceID),
+		fmt.Sprintf("features: logs=%v ui=%v swagger=%v profiling=%v watchCache=%v compression=%v",
+			s.enableLogsSupport, s.enableUISupport, s.enableSwaggerSupport, s.enableProfiling, s.enableWatchCache, s.enableCompression),
+		fmt.Sprintf("api versions: %s", strings.Join(s.installedGroupVersions, ", ")),
+		fmt.Sprintf("tls next protocols: %s", strings.Join(tlsNextProtos(s.tlsNextProtos), ", ")),
+		fmt.Sprintf("limits: minRequestTimeout=%v defaultListLimit=%d", s.MinRequestTimeout, s.defaultListLimit),
+		fmt.Sprintf("storage backends: %s", strings.Join(s.storageBackends, ", ")),
+	}
+}
+
+// serveStartupReport exposes the startup report over HTTP for diagnostics.
+func (s *GenericAPIServer) serveStartupReport(w http.ResponseWriter, req *http.Request) {
+	for _, line := range s.StartupReport() {
+		fmt.Fprintln(w, line)
+	}
+}
+
+// serveStoragePrefixes reports, for each API group, the storage key prefix in
+// use and the number of keys stored under it, so operators can verify a
+// prefix migration before cutting over. Read-only; backends that do not
+// implement storage.PrefixReporter are reported as such.
+func (s *GenericAPIServer) serveStoragePrefixes(w http.ResponseWriter, req *http.Request) {
+	groups := []string{}
+	for group := range s.storageDestinations.APIGroups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		name := group
+		if name == "" {
+			name = "legacy"
+		}
+		destinations := s.storageDestinations.APIGroups[group]
+		fmt.Fprintf(w, "group %s: %s\n", name, describeStoragePrefix(destinations.Default))
+		if len(destinations.PathPrefix) > 0 {
+			fmt.Fprintf(w, "group %s configured prefix: %s\n", name, destinations.PathPrefix)
+		}
+		resources := []string{}
+		for resource := range destinations.Overrides {
+			resources = append(resources, resource)
+		}
+		sort.Strings(resources)
+		for _, resource := range resources {
+			fmt.Fprintf(w, "group %s resource %s: %s\n", name, resource, describeStoragePrefix(destinations.Overrides[resource]))
+		}
+	}
+	if s.storageDestinations.Fallback != nil {
+		fmt.Fprintf(w, "fallback: %s\n", describeStoragePrefix(s.storageDestinations.Fallback))
+	}
+}
+
+// serveAPIMatrix returns a single JSON document enumerating every installed
+// group, version, resource and supported verb, so tooling can fetch the full
+// API surface in one call instead of aggregating per-version discovery. The
+// legacy group appears under the empty string key.
+func (s *GenericAPIServer) serveAPIMatrix(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.apiResourceMatrix); err != nil {
+		glog.Errorf("Failed to encode API resource matrix: %v", err)
+	}
+}
+
+func describeStoragePrefix(store storage.Interface) string {
+	if store == nil {
+		return "no storage configured"
+	}
+	reporter, ok := store.(storage.PrefixReporter)
+	if !ok {
+		return "backend does not report its prefix"
+	}
+	count, err := reporter.CountKeys(context.TODO())
+	if err != nil {
+		return fmt.Sprintf("prefix=%s keys=unknown (%v)", reporter.Prefix(), err)
+	}
+	return fmt.Sprintf("prefix=%s keys=%d", reporter.Prefix(), count)
+}
+
+// SetMaintenanceMode toggles maintenance mode: while on, API requests receive
+// a 503 with the given message and Retry-After interval, except for health,
+// metrics and the maintenance toggle endpoint.
+func (s *GenericAPIServer) SetMaintenanceMode(on bool, message string, retryAfter time.Duration) {
+	s.maintenanceMode.Set(on, message, retryAfter)
+	if on {
+		glog.Infof("Maintenance mode enabled: %s", message)
+	} else {
+		glog.Infof("Maintenance mode disabled")
+	}
+}
+
+// serveMaintenanceMode toggles maintenance mode over HTTP: PUT or POST with
+// on=true|false, and optional message and retryAfterSeconds parameters.
+func (s *GenericAPIServer) serveMaintenanceMode(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "PUT" && req.Method != "POST" {
+		http.Error(w, "Method not allowed, use PUT or POST.", http.StatusMethodNotAllowed)
+		return
+	}
+	on, err := strconv.ParseBool(req.URL.Query().Get("on"))
+	if err != nil {
+		http.Error(w, "The 'on' parameter must be true or false.", http.StatusBadRequest)
+		return
+	}
+	retryAfter := time.Duration(0)
+	if raw := req.URL.Query().Get("retryAfterSeconds"); len(raw) > 0 {
+		seconds, err := strconv.Atoi(raw)
+		if err != nil || seconds < 0 {
+			http.Error(w, "The 'retryAfterSeconds' parameter must be a non-negative integer.", http.StatusBadRequest)
+			return
+		}
+		retryAfter = time.Duration(seconds) * time.Second
+	}
+	s.SetMaintenanceMode(on, req.URL.Query().Get("message"), retryAfter)
+	fmt.Fprintf(w, "maintenance mode: %v\n", on)
+}
+
 func (s *GenericAPIServer) StorageDecorator() generic.StorageDecorator {
-	if s.enableWatchCache {
-		return genericetcd.StorageWithCacher
+	if !s.enableWatchCache {
+		return generic.UndecoratedStorage
+	}
+	cacherDecorator := genericetcd.StorageWithCacher
+	if s.relistExpiredWatches {
+		cacherDecorator = genericetcd.StorageWithRelistingCacher
+	}
+	if len(s.forceQuorumReads) == 0 && len(s.watchCacheSizes) == 0 && s.defaultWatchCacheSize == 0 {
+		return cacherDecorator
+	}
+	// Resources configured for quorum reads bypass the watch cache so every
+	// read hits etcd, regardless of any resourceVersion hints from clients.
+	// For the rest, the configured cache sizes override the capacity the
+	// registry requested.
+	return func(
+		storageInterface storage.Interface,
+		capacity int,
+		objectType runtime.Object,
+		resourcePrefix string,
+		namespaceScoped bool,
+		newListFunc func() runtime.Object) storage.Interface {
+		resource := strings.Trim(resourcePrefix, "/")
+		if s.forceQuorumReads[resource] {
+			return generic.UndecoratedStorage(storageInterface, capacity, objectType, resourcePrefix, namespaceScoped, newListFunc)
+		}
+		if size, ok := s.watchCacheSizes[resource]; ok {
+			capacity = size
+		} else if s.defaultWatchCacheSize > 0 {
+			capacity = s.defaultWatchCacheSize
+		}
+		return cacherDecorator(storageInterface, capacity, objectType, resourcePrefix, namespaceScoped, newListFunc)
 	}
-	return generic.UndecoratedStorage
 }
 
 // setDefaults fills in any fields not set that are required to have valid data.
-func setDefaults(c *Config) {
+func setDefaults(c *Config) error {
 	if c.ServiceClusterIPRange == nil {
 		defaultNet := "10.0.0.0/24"
 		glog.Warningf("Network range for service cluster IPs is unspecified. Defaulting to %v.", defaultNet)
 		_, serviceClusterIPRange, err := net.ParseCIDR(defaultNet)
 		if err != nil {
-			glog.Fatalf("Unable to parse CIDR: %v", err)
-		}
-		if size := ipallocator.RangeSize(serviceClusterIPRange); size < 8 {
-			glog.Fatalf("The service cluster IP range must be at least %d IP addresses", 8)
+			return fmt.Errorf("unable to parse CIDR: %v", err)
 		}
 		c.ServiceClusterIPRange = serviceClusterIPRange
 	}
+	if size := ipallocator.RangeSize(c.ServiceClusterIPRange); size < 8 {
+		return fmt.Errorf("the service cluster IP range must be at least %d IP addresses", 8)
+	}
 	if c.ServiceReadWriteIP == nil {
 		// Select the first valid IP from ServiceClusterIPRange to use as the GenericAPIServer service IP.
 		serviceReadWriteIP, err := ipallocator.GetIndexedIP(c.ServiceClusterIPRange, 1)
 		if err != nil {
-			glog.Fatalf("Failed to generate service read-write IP for GenericAPIServer service: %v", err)
+			return fmt.Errorf("failed to generate service read-write IP for GenericAPIServer service: %v", err)
 		}
 		glog.V(4).Infof("Setting GenericAPIServer service IP to %q (read-write).", serviceReadWriteIP)
 		c.ServiceReadWriteIP = serviceReadWriteIP
+	} else if !c.ServiceClusterIPRange.Contains(c.ServiceReadWriteIP) {
+		return fmt.Errorf("service read-write IP %v is not within the service cluster IP range %v", c.ServiceReadWriteIP, c.ServiceClusterIPRange)
 	}
 	if c.ServiceNodePortRange.Size == 0 {
 		// TODO: Currently no way to specify an empty range (do we need to allow this?)
@@ -336,6 +1125,27 @@ func setDefaults(c *Config) {
 	if c.RequestContextMapper == nil {
 		c.RequestContextMapper = api.NewRequestContextMapper()
 	}
+	if c.WaitForStorageAtStartup && c.StorageWaitTimeout == 0 {
+		c.StorageWaitTimeout = time.Minute
+	}
+	if c.HealthzBackendTimeout == 0 {
+		c.HealthzBackendTimeout = 2 * time.Second
+	}
+	if c.ReadinessCacheTTL == 0 {
+		c.ReadinessCacheTTL = 5 * time.Second
+	}
+	return nil
+}
+
+// DeprecationWarnings returns a warning for each deprecated Config field that
+// is set, so embedders can surface them to operators before the fields are
+// removed. New logs the same warnings at startup.
+func (c *Config) DeprecationWarnings() []string {
+	warnings := []string{}
+	if c.SupportsBasicAuth {
+		warnings = append(warnings, "Config.SupportsBasicAuth is deprecated and will be removed once the server no longer supports http basic auth; migrate clients to token or certificate authentication")
+	}
+	return warnings
 }
 
 // New returns a new instance of GenericAPIServer from the given config.
@@ -361,7 +1171,33 @@ func setDefaults(c *Config) {
 //   auth, then the caller should create a handler for those endpoints, which delegates the
 //   any unhandled paths to "Handler".
 func New(c *Config) *GenericAPIServer {
-	setDefaults(c)
+	s, err := NewWithError(c)
+	if err != nil {
+		glog.Fatalf("Invalid GenericAPIServer config: %v", err)
+	}
+	return s
+}
+
+// NewWithError is New, except that config validation failures are returned as
+// an error instead of terminating the process, so embedders can validate a
+// Config before committing to starting anything.
+func NewWithError(c *Config) (*GenericAPIServer, error) {
+	if err := setDefaults(c); err != nil {
+		return nil, err
+	}
+	for _, warning := range c.DeprecationWarnings() {
+		glog.Warningf("DEPRECATED: %s", warning)
+	}
+	if err := validateTLSNextProtos(c.TLSNextProtos); err != nil {
+		return nil, fmt.Errorf("invalid TLSNextProtos: %v", err)
+	}
+	if len(c.LatencyBuckets) > 0 {
+		metrics.SetLatencyBuckets(c.LatencyBuckets)
+	}
+	admissionControl := c.AdmissionControl
+	if admissionControl != nil && c.MaxConcurrentAdmission > 0 {
+		admissionControl = newLimitedAdmission(admissionControl, c.MaxConcurrentAdmission)
+	}
 
 	s := &GenericAPIServer{
 		ServiceClusterIPRange:    c.ServiceClusterIPRange,
@@ -370,20 +1206,51 @@ func New(c *Config) *GenericAPIServer {
 		enableLogsSupport:        c.EnableLogsSupport,
 		enableUISupport:          c.EnableUISupport,
 		enableSwaggerSupport:     c.EnableSwaggerSupport,
+		swaggerWebServicesURL:    c.SwaggerWebServicesURL,
 		enableProfiling:          c.EnableProfiling,
+		enableMetrics:            c.EnableMetrics,
 		enableWatchCache:         c.EnableWatchCache,
+		watchCacheSizes:          c.WatchCacheSizes,
+		defaultWatchCacheSize:    c.WatchCacheSize,
+		relistExpiredWatches:     c.RelistExpiredWatches,
+		forceQuorumReads:         c.ForceQuorumReads,
 		APIPrefix:                c.APIPrefix,
 		APIGroupPrefix:           c.APIGroupPrefix,
 		corsAllowedOriginList:    c.CorsAllowedOriginList,
 		authenticator:            c.Authenticator,
 		authorizer:               c.Authorizer,
-		AdmissionControl:         c.AdmissionControl,
+		AdmissionControl:         admissionControl,
 		ApiGroupVersionOverrides: c.APIGroupVersionOverrides,
 		RequestContextMapper:     c.RequestContextMapper,
 
 		cacheTimeout:      c.CacheTimeout,
 		MinRequestTimeout: time.Duration(c.MinRequestTimeout) * time.Second,
 
+		connectionKeepAlivePeriod: c.ConnectionKeepAlivePeriod,
+		tlsNextProtos:             c.TLSNextProtos,
+
+		enableTLSNegotiationMetrics: c.EnableTLSNegotiationMetrics,
+
+		defaultListLimit:   c.DefaultListLimit,
+		listLimitOverrides: c.ListLimitOverrides,
+
+		enableCompression: c.EnableCompression,
+
+		waitForStorageAtStartup: c.WaitForStorageAtStartup,
+		storageWaitTimeout:      c.StorageWaitTimeout,
+
+		emitTimeoutHeader:   c.EmitTimeoutHeader,
+		emitFairnessHeaders: c.EmitFairnessHeaders,
+
+		streamingDecode: c.StreamingDecode,
+
+		jsonNumberAsString:  c.JSONNumberAsString,
+		jsonTimestampFormat: c.JSONTimestampFormat,
+
+		readOnlyPort: c.ReadOnlyPort,
+
+		maxConnectionsPerIP: c.MaxConnectionsPerIP,
+
 		MasterCount:         c.MasterCount,
 		externalHost:        c.ExternalHost,
 		ClusterIP:           c.PublicAddress,
@@ -397,6 +1264,18 @@ func New(c *Config) *GenericAPIServer {
 		KubernetesServiceNodePort: c.KubernetesServiceNodePort,
 	}
 
+	// Track service cluster IP allocations so embedders can carve out
+	// additional IPs consistently with the apiserver's own allocation.
+	if c.ServiceClusterIPRange != nil {
+		serviceIPAllocator := ipallocator.NewCIDRRange(c.ServiceClusterIPRange)
+		if c.ServiceReadWriteIP != nil {
+			if err := serviceIPAllocator.Allocate(c.ServiceReadWriteIP); err != nil {
+				return nil, fmt.Errorf("unable to reserve the service read-write IP %v: %v", c.ServiceReadWriteIP, err)
+			}
+		}
+		s.serviceIPAllocator = serviceIPAllocator
+	}
+
 	var handlerContainer *restful.Container
 	if c.RestfulContainer != nil {
 		s.mux = c.RestfulContainer.ServeMux
@@ -410,10 +1289,13 @@ func New(c *Config) *GenericAPIServer {
 	// Use CurlyRouter to be able to use regular expressions in paths. Regular expressions are required in paths for example for proxy (where the path is proxy/{kind}/{name}/{*})
 	s.HandlerContainer.Router(restful.CurlyRouter{})
 	s.MuxHelper = &apiserver.MuxHelper{Mux: s.mux, RegisteredPaths: []string{}}
+	// Serve a negotiated Status for routing failures even before any API
+	// group installs its own service error handler.
+	apiserver.InstallServiceErrorHandler(s.HandlerContainer, s.NewRequestInfoResolver(), []string{})
 
 	s.init(c)
 
-	return s
+	return s, nil
 }
 
 func (s *GenericAPIServer) NewRequestInfoResolver() *apiserver.RequestInfoResolver {
@@ -443,9 +1325,23 @@ func (s *GenericAPIServer) HandleFuncWithAuth(pattern string, handler func(http.
 	s.MuxHelper.HandleFunc(pattern, handler)
 }
 
+// AddWebService adds a go-restful WebService to the server's handler
+// container, so custom non-REST endpoints participate in CurlyRouter routing
+// and, unlike handlers added via HandleWithAuth, appear in swagger
+// discovery. It must be called before InstallSwaggerAPI for the endpoints to
+// be included in the spec.
+func (s *GenericAPIServer) AddWebService(ws *restful.WebService) {
+	s.HandlerContainer.Add(ws)
+}
+
 func NewHandlerContainer(mux *http.ServeMux) *restful.Container {
 	container := restful.NewContainer()
 	container.ServeMux = mux
+	// Answer OPTIONS on registered paths with an Allow header listing the
+	// methods of the matching routes. Routes exist only for the verbs a
+	// resource's storage implements, so the header reflects what the
+	// resource actually supports.
+	container.Filter(container.OPTIONSFilter)
 	apiserver.InstallRecoverHandler(container)
 	return container
 }
@@ -453,7 +1349,80 @@ func NewHandlerContainer(mux *http.ServeMux) *restful.Container {
 // init initializes GenericAPIServer.
 func (s *GenericAPIServer) init(c *Config) {
 
-	if c.ProxyDialer != nil || c.ProxyTLSClientConfig != nil {
+	userAgentNormalizer, err := apiserver.NewUserAgentNormalizer(c.UserAgentPatterns)
+	if err != nil {
+		glog.Fatalf("Invalid user agent pattern: %v", err)
+	}
+	s.userAgentNormalizer = userAgentNormalizer
+
+	if c.CompactionInterval != 0 {
+		if c.Compactor == nil {
+			glog.Fatalf("CompactionInterval is set but no Compactor is configured")
+		}
+		lockStorage := c.StorageDestinations.Get(api.GroupName, "")
+		if lockStorage == nil {
+			glog.Fatalf("CompactionInterval is set but no storage is configured for the legacy group")
+		}
+		s.compactionCoordinator = &compactionCoordinator{
+			storage:   lockStorage,
+			compactor: c.Compactor,
+			interval:  c.CompactionInterval,
+		}
+	}
+
+	// With multiple masters each triggering etcd compaction independently,
+	// compactions conflict; coordinate them through the compaction lock
+	// instead, compacting every backend that supports it.
+	if c.EtcdCompactionInterval != 0 && c.MasterCount > 1 && s.compactionCoordinator == nil {
+		lockStorage := c.StorageDestinations.Get(api.GroupName, "")
+		if lockStorage == nil {
+			glog.Fatalf("EtcdCompactionInterval is set but no storage is configured for the legacy group")
+		}
+		s.compactionCoordinator = &compactionCoordinator{
+			storage:   lockStorage,
+			compactor: &storageCompactor{destinations: c.StorageDestinations},
+			interval:  c.EtcdCompactionInterval,
+		}
+	}
+
+	s.instanceID = newInstanceID()
+	s.storageBackends = c.StorageDestinations.Backends()
+	s.storageDestinations = c.StorageDestinations
+	s.healthzBackendTimeout = c.HealthzBackendTimeout
+	s.readinessCacheTTL = c.ReadinessCacheTTL
+	// The root handler re-reads the registered checks on every request, so
+	// callers may keep adding checks via AddHealthzCheck after startup.
+	s.MuxHelper.Handle("/healthz", healthz.DynamicHandler(s.healthzCheckers))
+	// Unlike /healthz, /readyz caches the backend health result briefly so
+	// aggressive load balancer probing does not translate into etcd pings.
+	s.MuxHelper.HandleFunc("/readyz", s.serveReadyz)
+	// Let load balancers detect an apiserver partitioned from its storage.
+	if len(s.storageBackends) > 0 {
+		s.MuxHelper.HandleFunc("/healthz/etcd", s.serveEtcdHealthz)
+	}
+	if c.IdentityLeaseTTL != 0 {
+		leaseStorage := c.StorageDestinations.Get(api.GroupName, "")
+		if leaseStorage == nil {
+			glog.Fatalf("IdentityLeaseTTL is set but no storage is configured for the legacy group")
+		}
+		s.identityLeaseController = &identityLeaseController{
+			storage:    leaseStorage,
+			instanceID: s.instanceID,
+			ttl:        c.IdentityLeaseTTL,
+		}
+	}
+
+	if c.EnableWarmStandby {
+		leaderStorage := c.StorageDestinations.Get(api.GroupName, "")
+		if leaderStorage == nil {
+			glog.Fatalf("EnableWarmStandby is set but no storage is configured for the legacy group")
+		}
+		s.standbyController = newStandbyController(leaderStorage, s.instanceID, c.StandbyLeaseTTL)
+	}
+
+	if c.ProxyTransport != nil {
+		s.ProxyTransport = c.ProxyTransport
+	} else if c.ProxyDialer != nil || c.ProxyTLSClientConfig != nil {
 		s.ProxyTransport = util.SetTransportDefaults(&http.Transport{
 			Dial:            c.ProxyDialer,
 			TLSClientConfig: c.ProxyTLSClientConfig,
@@ -464,7 +1433,7 @@ func (s *GenericAPIServer) init(c *Config) {
 	// We do not register this using restful Webservice since we do not want to surface this in api docs.
 	// Allow GenericAPIServer to be embedded in contexts which already have something registered at the root
 	if c.EnableIndex {
-		s.mux.HandleFunc("/", apiserver.IndexHandler(s.HandlerContainer, s.MuxHelper))
+		s.mux.HandleFunc("/", apiserver.IndexHandler(s.ListedPaths))
 	}
 
 	if c.EnableLogsSupport {
@@ -478,6 +1447,13 @@ func (s *GenericAPIServer) init(c *Config) {
 		s.mux.HandleFunc("/debug/pprof/", pprof.Index)
 		s.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
 		s.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		s.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		s.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+
+	if c.EnableMetrics {
+		metrics.Register()
+		s.MuxHelper.Handle("/metrics", prometheus.Handler())
 	}
 
 	handler := http.Handler(s.mux.(*http.ServeMux))
@@ -491,97 +1467,585 @@ func (s *GenericAPIServer) init(c *Config) {
 		if err != nil {
 			glog.Fatalf("Invalid CORS allowed origin, --cors-allowed-origins flag was set to %v - %v", strings.Join(c.CorsAllowedOriginList, ","), err)
 		}
-		handler = apiserver.CORS(handler, allowedOriginRegexps, nil, nil, "true")
+		handler = apiserver.CORS(handler, allowedOriginRegexps, c.CorsAllowedMethods, c.CorsAllowedHeaders, "true", c.CorsMaxAge)
 	}
 
 	s.InsecureHandler = handler
+	if c.RequireTLSForMutations {
+		s.InsecureHandler = apiserver.RequireTLSForMutations(s.InsecureHandler)
+	}
+
+	buildHandlerChain := c.BuildHandlerChain
+	if buildHandlerChain == nil {
+		buildHandlerChain = DefaultBuildHandlerChain
+	}
+	// TODO: Make this optional?  Consumers of GenericAPIServer depend on this currently.
+	s.Handler = buildHandlerChain(handler, c)
+
+	if c.AuditWriter != nil {
+		// Audit outside the auth filters so the user identity is already in
+		// the request context when the line is written after the response.
+		attributeGetter := apiserver.NewRequestAttributeGetter(s.RequestContextMapper, s.NewRequestInfoResolver())
+		s.Handler = apiserver.WithAudit(s.Handler, attributeGetter, c.AuditWriter)
+		s.InsecureHandler = apiserver.WithAudit(s.InsecureHandler, attributeGetter, c.AuditWriter)
+	}
 
-	attributeGetter := apiserver.NewRequestAttributeGetter(s.RequestContextMapper, s.NewRequestInfoResolver())
-	handler = apiserver.WithAuthorizationCheck(handler, attributeGetter, s.authorizer)
+	if c.RejectAmbiguousFraming {
+		s.Handler = apiserver.WithFramingCheck(s.Handler)
+		s.InsecureHandler = apiserver.WithFramingCheck(s.InsecureHandler)
+	}
 
-	// Install Authenticator
-	if c.Authenticator != nil {
-		authenticatedHandler, err := handlers.NewRequestAuthenticator(s.RequestContextMapper, c.Authenticator, handlers.Unauthorized(c.SupportsBasicAuth), handler)
-		if err != nil {
-			glog.Fatalf("Could not initialize authenticator: %v", err)
+	if c.RequireContentTypeOnWrites || len(c.DefaultRequestContentType) > 0 {
+		s.Handler = apiserver.WithContentTypeCheck(s.Handler, c.DefaultRequestContentType, c.RequireContentTypeOnWrites)
+		s.InsecureHandler = apiserver.WithContentTypeCheck(s.InsecureHandler, c.DefaultRequestContentType, c.RequireContentTypeOnWrites)
+	}
+
+	if len(c.AllowedQueryParams) > 0 {
+		allowed := map[string]sets.String{}
+		for verb, params := range c.AllowedQueryParams {
+			allowed[verb] = sets.NewString(params...)
 		}
-		handler = authenticatedHandler
+		resolver := s.NewRequestInfoResolver()
+		s.Handler = apiserver.WithQueryParamAllowlist(s.Handler, resolver, allowed)
+		s.InsecureHandler = apiserver.WithQueryParamAllowlist(s.InsecureHandler, resolver, allowed)
 	}
 
-	// TODO: Make this optional?  Consumers of GenericAPIServer depend on this currently.
-	s.Handler = handler
+	if c.RejectHTTP10Watches {
+		s.Handler = apiserver.WithHTTP10WatchRejection(s.Handler)
+		s.InsecureHandler = apiserver.WithHTTP10WatchRejection(s.InsecureHandler)
+	}
+
+	if c.ChaosLatencyConfig != nil {
+		if !c.ChaosLatencyConfig.UnsafeAllowChaos {
+			glog.Fatalf("ChaosLatencyConfig is set without UnsafeAllowChaos; refusing to start")
+		}
+		glog.Warningf("Chaos latency injection is enabled; this must never be used in production")
+		s.Handler = apiserver.WithChaosLatency(s.Handler, c.ChaosLatencyConfig)
+		s.InsecureHandler = apiserver.WithChaosLatency(s.InsecureHandler, c.ChaosLatencyConfig)
+	}
+
+	if len(c.PathRewriteRules) > 0 {
+		// Applied outside the auth filters so authentication and
+		// authorization see the rewritten (real) resource path.
+		s.Handler = apiserver.WithPathRewrites(s.Handler, c.PathRewriteRules)
+		s.InsecureHandler = apiserver.WithPathRewrites(s.InsecureHandler, c.PathRewriteRules)
+	}
+
+	// Maintenance mode rejects API traffic while keeping health checks,
+	// metrics, and its own toggle endpoint reachable.
+	s.HandleFuncWithAuth("/admin/maintenance", s.serveMaintenanceMode)
+	s.HandleFuncWithAuth("/admin/startupreport", s.serveStartupReport)
+	s.HandleFuncWithAuth("/admin/storageprefixes", s.serveStoragePrefixes)
+	s.HandleFuncWithAuth("/admin/apimatrix", s.serveAPIMatrix)
+	s.HandleFuncWithAuth("/admin/role", s.serveRole)
+	if len(c.AllowedHosts) > 0 {
+		// The server's own advertised addresses are always allowed.
+		allowedHosts := c.AllowedHosts
+		if len(c.ExternalHost) > 0 {
+			allowedHosts = append(allowedHosts, c.ExternalHost)
+		}
+		if c.PublicAddress != nil {
+			allowedHosts = append(allowedHosts, c.PublicAddress.String())
+		}
+		s.Handler = apiserver.WithHostAllowlist(s.Handler, allowedHosts)
+		s.InsecureHandler = apiserver.WithHostAllowlist(s.InsecureHandler, allowedHosts)
+	}
+
+	if s.standbyController != nil {
+		s.Handler = apiserver.WithStandbyMode(s.Handler, s.standbyController.Active)
+		s.InsecureHandler = apiserver.WithStandbyMode(s.InsecureHandler, s.standbyController.Active)
+	}
+
+	maintenanceExempt := []string{"/healthz", "/metrics", "/admin/maintenance"}
+	s.Handler = apiserver.WithMaintenanceMode(s.Handler, &s.maintenanceMode, maintenanceExempt)
+	s.InsecureHandler = apiserver.WithMaintenanceMode(s.InsecureHandler, &s.maintenanceMode, maintenanceExempt)
+
+	if c.EnableMetrics {
+		// Instrument outside the other filters so requests rejected before
+		// routing (maintenance mode, host allowlist, ...) are still counted.
+		s.Handler = metrics.InstrumentHandler(s.Handler)
+		s.InsecureHandler = metrics.InstrumentHandler(s.InsecureHandler)
+	}
+
+	// Attach the request deadline to the stored context so storage calls are
+	// cancelled when the HTTP timeout fires. The timeout function itself is
+	// installed by Run once the serving options are known; until then no
+	// deadline is attached.
+	s.Handler = apiserver.WithRequestDeadline(s.Handler, s.RequestContextMapper, s.requestDeadline)
+	s.InsecureHandler = apiserver.WithRequestDeadline(s.InsecureHandler, s.RequestContextMapper, s.requestDeadline)
 
 	// After all wrapping is done, put a context filter around both handlers
-	if handler, err := api.NewRequestContextFilter(s.RequestContextMapper, s.Handler); err != nil {
+	if handler, err := s.newRequestContextFilter(c, s.Handler); err != nil {
 		glog.Fatalf("Could not initialize request context filter: %v", err)
 	} else {
 		s.Handler = handler
 	}
 
-	if handler, err := api.NewRequestContextFilter(s.RequestContextMapper, s.InsecureHandler); err != nil {
+	if handler, err := s.newRequestContextFilter(c, s.InsecureHandler); err != nil {
 		glog.Fatalf("Could not initialize request context filter: %v", err)
 	} else {
 		s.InsecureHandler = handler
 	}
 }
 
-// Exposes the given group versions in API.
-func (s *GenericAPIServer) InstallAPIGroups(groupsInfo []APIGroupInfo) error {
-	for _, apiGroupInfo := range groupsInfo {
-		if err := s.installAPIGroup(&apiGroupInfo); err != nil {
-			return err
+// newRequestInfoResolver mirrors GenericAPIServer.NewRequestInfoResolver for
+// use before the server exists, e.g. inside a handler chain builder.
+func (c *Config) newRequestInfoResolver() *apiserver.RequestInfoResolver {
+	return &apiserver.RequestInfoResolver{
+		sets.NewString(strings.Trim(c.APIPrefix, "/"), strings.Trim(c.APIGroupPrefix, "/")), // all possible API prefixes
+		sets.NewString(strings.Trim(c.APIPrefix, "/")),                                      // APIPrefixes that won't have groups (legacy)
+	}
+}
+
+// DefaultBuildHandlerChain wraps the API handler with the standard secure
+// serving filters: per-user watch limiting, authorization, client certificate
+// expiry checks, authentication, certificate attribute extraction and request
+// correlation IDs. It is used when Config.BuildHandlerChain is nil and is
+// exported so custom chains can reuse it as a building block.
+func DefaultBuildHandlerChain(apiHandler http.Handler, c *Config) http.Handler {
+	handler := apiHandler
+
+	// Wrapped before the authenticator so the user is already in the request
+	// context when the watch is counted.
+	if c.MaxWatchesPerUser > 0 {
+		handler = apiserver.MaxWatchesPerUser(handler, c.MaxWatchesPerUser, c.RequestContextMapper)
+	}
+
+	// Install Authorizer
+	if c.Authorizer != nil {
+		attributeGetter := apiserver.NewRequestAttributeGetter(c.RequestContextMapper, c.newRequestInfoResolver())
+		deniedAudit := c.DeniedAuthorizationAudit
+		if deniedAudit == nil {
+			deniedAudit = apiserver.LogDeniedAuthorization
+		}
+		handler = apiserver.WithAuthorizationCheckAndAudit(handler, attributeGetter, c.Authorizer, deniedAudit)
+	}
+
+	handler = apiserver.WithClientCertExpiryCheck(handler, c.ClientCertExpiryGrace, c.ClientCertExpiryWarning)
+
+	// Install Authenticator
+	if c.Authenticator != nil {
+		failedHandler := c.UnauthorizedHandler
+		if failedHandler == nil {
+			failedHandler = handlers.Unauthorized(c.SupportsBasicAuth)
 		}
+		authenticatedHandler, err := handlers.NewRequestAuthenticator(c.RequestContextMapper, c.Authenticator, failedHandler, handler)
+		if err != nil {
+			glog.Fatalf("Could not initialize authenticator: %v", err)
+		}
+		handler = authenticatedHandler
+	}
+
+	if c.CertAttributeExtractor != nil {
+		handler = apiserver.WithCertAttributes(handler, c.RequestContextMapper, c.CertAttributeExtractor)
+	}
+
+	if len(c.RequestIDHeader) > 0 {
+		handler = apiserver.WithRequestID(handler, c.RequestIDHeader, c.RequestContextMapper)
 	}
+
+	return handler
+}
+
+// serveRole reports whether this apiserver is the active instance or a warm
+// standby, and in the latter case which instance currently leads.
+func (s *GenericAPIServer) serveRole(w http.ResponseWriter, req *http.Request) {
+	if s.standbyController == nil {
+		fmt.Fprintln(w, "active (warm standby mode disabled)")
+		return
+	}
+	if active, leader := s.standbyController.Active(); active {
+		fmt.Fprintln(w, "active")
+	} else if len(leader) > 0 {
+		fmt.Fprintf(w, "standby (leader: %s)\n", leader)
+	} else {
+		fmt.Fprintln(w, "standby (leader unknown)")
+	}
+}
+
+// OnActive registers fn to run once this instance becomes the active
+// apiserver. With warm standby mode disabled the callback runs immediately;
+// otherwise it is deferred until the leader lease is acquired. Use this for
+// singleton controllers that must not run on a standby.
+func (s *GenericAPIServer) OnActive(fn func()) {
+	if s.standbyController == nil {
+		go func() {
+			defer util.HandleCrash()
+			fn()
+		}()
+		return
+	}
+	s.standbyController.OnActive(fn)
+}
+
+// AllocateServiceIP hands out an unused cluster IP from the configured
+// ServiceClusterIPRange, consistent with the apiserver's own allocation: the
+// service read-write IP is already reserved.
+func (s *GenericAPIServer) AllocateServiceIP() (net.IP, error) {
+	if s.serviceIPAllocator == nil {
+		return nil, fmt.Errorf("no service cluster IP range is configured")
+	}
+	return s.serviceIPAllocator.AllocateNext()
+}
+
+// ReleaseServiceIP returns a cluster IP obtained from AllocateServiceIP to
+// the pool.
+func (s *GenericAPIServer) ReleaseServiceIP(ip net.IP) error {
+	if s.serviceIPAllocator == nil {
+		return fmt.Errorf("no service cluster IP range is configured")
+	}
+	return s.serviceIPAllocator.Release(ip)
+}
+
+// postStartHook pairs a startup callback with the name it was registered
+// under.
+type postStartHook struct {
+	name string
+	fn   func(*GenericAPIServer) error
+}
+
+// AddPostStartHook registers fn to run at the start of Run, after the server
+// is fully initialized but before any listener is created. Hooks run in
+// registration order, and a failing hook aborts startup. Names must be
+// unique, so shared libraries can detect duplicate registration.
+func (s *GenericAPIServer) AddPostStartHook(name string, fn func(*GenericAPIServer) error) error {
+	for _, hook := range s.postStartHooks {
+		if hook.name == name {
+			return fmt.Errorf("post-start hook %q is already registered", name)
+		}
+	}
+	s.postStartHooks = append(s.postStartHooks, postStartHook{name: name, fn: fn})
 	return nil
 }
 
+// AddHealthzCheck registers a named health check with the /healthz handler.
+// Checks may be registered at any time, including after the server has
+// started serving. Names must be unique.
+func (s *GenericAPIServer) AddHealthzCheck(name string, check func() error) error {
+	s.healthzLock.Lock()
+	defer s.healthzLock.Unlock()
+	for _, existing := range s.healthzChecks {
+		if existing.Name() == name {
+			return fmt.Errorf("healthz check %q is already registered", name)
+		}
+	}
+	s.healthzChecks = append(s.healthzChecks, healthz.NamedCheck(name, func(_ *http.Request) error {
+		return check()
+	}))
+	return nil
+}
+
+// healthzCheckers snapshots the registered health checks for the /healthz
+// handler.
+func (s *GenericAPIServer) healthzCheckers() []healthz.HealthzChecker {
+	s.healthzLock.Lock()
+	defer s.healthzLock.Unlock()
+	return append([]healthz.HealthzChecker{}, s.healthzChecks...)
+}
+
+// maxInFlightCheck returns a health check that fails once the in-flight
+// request semaphore has been fully saturated for longer than
+// saturationTimeout. A briefly full semaphore just means the server is busy;
+// one that stays full usually means request handling is wedged (e.g. on a
+// deadlocked mutex) while the listener keeps accepting connections.
+func maxInFlightCheck(sem chan bool, saturationTimeout time.Duration) func() error {
+	var lock sync.Mutex
+	lastAvailable := time.Now()
+	return func() error {
+		lock.Lock()
+		defer lock.Unlock()
+		select {
+		case sem <- true:
+			<-sem
+			lastAvailable = time.Now()
+			return nil
+		default:
+		}
+		if saturated := time.Since(lastAvailable); saturated > saturationTimeout {
+			return fmt.Errorf("in-flight request limit saturated for %v", saturated)
+		}
+		return nil
+	}
+}
+
+// requestDeadline reports the timeout to attach as a deadline to the
+// request's context, or zero when none applies (long-running requests, or
+// before Run has installed the timeout function).
+func (s *GenericAPIServer) requestDeadline(req *http.Request) time.Duration {
+	if s.requestTimeoutFunc == nil {
+		return 0
+	}
+	return s.requestTimeoutFunc(req)
+}
+
+// newRequestContextFilter wraps the handler with the request context filter,
+// attaching the configured context cleaner when one is provided.
+func (s *GenericAPIServer) newRequestContextFilter(c *Config, handler http.Handler) (http.Handler, error) {
+	if c.RequestContextCleaner != nil {
+		return api.NewRequestContextFilterWithCleanup(s.RequestContextMapper, c.RequestContextCleaner, handler)
+	}
+	return api.NewRequestContextFilter(s.RequestContextMapper, handler)
+}
+
+// Exposes the given group versions in API. Groups are installed in an order
+// satisfying any dependencies declared via APIGroupInfo.DependsOn. Group
+// versions whose path is already registered are skipped rather than failing
+// the whole call, so a group may safely be installed twice (e.g. by a plugin
+// registering the same extension group as core); the skipped group versions
+// are returned.
+func (s *GenericAPIServer) InstallAPIGroups(groupsInfo []APIGroupInfo) ([]string, error) {
+	ordered, err := orderAPIGroups(groupsInfo)
+	if err != nil {
+		return nil, err
+	}
+	skipped := []string{}
+	for _, apiGroupInfo := range ordered {
+		groupSkipped, err := s.installAPIGroup(&apiGroupInfo)
+		if err != nil {
+			return nil, err
+		}
+		skipped = append(skipped, groupSkipped...)
+	}
+	return skipped, nil
+}
+
+// orderAPIGroups returns the given groups reordered so that every group
+// appears after the groups it declares in DependsOn. Groups without
+// dependencies keep their relative order. A dependency on a group not being
+// installed, or a dependency cycle, is an error.
+func orderAPIGroups(groupsInfo []APIGroupInfo) ([]APIGroupInfo, error) {
+	indexByName := map[string]int{}
+	for i := range groupsInfo {
+		indexByName[groupsInfo[i].GroupMeta.GroupVersion.Group] = i
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(groupsInfo))
+	ordered := make([]APIGroupInfo, 0, len(groupsInfo))
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visiting:
+			return fmt.Errorf("dependency cycle involving API group %q", groupsInfo[i].GroupMeta.GroupVersion.Group)
+		case visited:
+			return nil
+		}
+		state[i] = visiting
+		for _, dep := range groupsInfo[i].DependsOn {
+			j, ok := indexByName[dep]
+			if !ok {
+				return fmt.Errorf("API group %q depends on %q, which is not being installed", groupsInfo[i].GroupMeta.GroupVersion.Group, dep)
+			}
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		ordered = append(ordered, groupsInfo[i])
+		return nil
+	}
+	for i := range groupsInfo {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// serveEtcdHealthz reports whether every storage backend is reachable: 200
+// when all respond within the configured timeout, 503 listing the failing
+// backends otherwise.
+// serveReadyz reports whether the server's storage backends are reachable,
+// reusing the last result for readinessCacheTTL so frequent probes are cheap.
+func (s *GenericAPIServer) serveReadyz(w http.ResponseWriter, req *http.Request) {
+	s.readyzLock.Lock()
+	if time.Since(s.lastReadyCheck) >= s.readinessCacheTTL {
+		s.lastReadyErr = s.checkStorageBackends()
+		s.lastReadyCheck = time.Now()
+	}
+	err := s.lastReadyErr
+	s.readyzLock.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprint(w, "ok")
+}
+
+func (s *GenericAPIServer) serveEtcdHealthz(w http.ResponseWriter, req *http.Request) {
+	if err := s.checkStorageBackends(); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprint(w, "ok")
+}
+
+// checkStorageBackends returns an error listing the storage backends that do
+// not respond within the configured timeout.
+func (s *GenericAPIServer) checkStorageBackends() error {
+	failing := []string{}
+	for _, backend := range s.storageBackends {
+		if err := dialStorageBackend(backend, s.healthzBackendTimeout); err != nil {
+			failing = append(failing, fmt.Sprintf("%s (%v)", backend, err))
+		}
+	}
+	if len(failing) > 0 {
+		return fmt.Errorf("unhealthy storage backends: %s", strings.Join(failing, "; "))
+	}
+	return nil
+}
+
+// dialStorageBackend reports whether a single backend (given as a URL such as
+// "http://127.0.0.1:4001") accepts a TCP connection.
+func dialStorageBackend(backend string, timeout time.Duration) error {
+	backendURL, err := url.Parse(backend)
+	if err != nil {
+		return err
+	}
+	host := backendURL.Host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		// etcd default port.
+		host = net.JoinHostPort(host, "4001")
+	}
+	conn, err := net.DialTimeout("tcp", host, timeout)
+	if err != nil {
+		return err
+	}
+	conn.Close()
+	return nil
+}
+
+// waitForStorageBackends blocks until every storage backend is reachable or
+// the timeout expires, so a server configured to gate startup on storage does
+// not start answering requests it cannot serve.
+func waitForStorageBackends(backends []string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		unreachable := []string{}
+		for _, backend := range backends {
+			if err := dialStorageBackend(backend, 2*time.Second); err != nil {
+				unreachable = append(unreachable, fmt.Sprintf("%s (%v)", backend, err))
+			}
+		}
+		if len(unreachable) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("storage backends still unreachable after %v: %s", timeout, strings.Join(unreachable, "; "))
+		}
+		glog.Infof("Waiting for storage backends: %s", strings.Join(unreachable, "; "))
+		time.Sleep(time.Second)
+	}
+}
+
 func (s *GenericAPIServer) Run(options *ServerRunOptions) {
+	for _, line := range s.StartupReport() {
+		glog.Infof("Startup report: %s", line)
+	}
+
+	if s.waitForStorageAtStartup {
+		if err := waitForStorageBackends(s.storageBackends, s.storageWaitTimeout); err != nil {
+			glog.Fatalf("Storage readiness check failed: %v", err)
+		}
+	}
+
+	// Run the registered post-start hooks before any listener is created, so
+	// embedders can bootstrap initial objects or warm caches while the server
+	// is fully initialized but not yet serving.
+	for _, hook := range s.postStartHooks {
+		if err := hook.fn(s); err != nil {
+			glog.Fatalf("Post-start hook %q failed: %v", hook.name, err)
+		}
+	}
+
+	// The block profile served under /debug/pprof/block stays empty until a
+	// sampling rate is set, so enable it together with the endpoints.
+	if s.enableProfiling && options.ProfilingBlockRate > 0 {
+		goruntime.SetBlockProfileRate(options.ProfilingBlockRate)
+	}
+
 	// We serve on 2 ports.  See docs/accessing_the_api.md
-	secureLocation := ""
+	secureLocations := []string{}
 	if options.SecurePort != 0 {
-		secureLocation = net.JoinHostPort(options.BindAddress.String(), strconv.Itoa(options.SecurePort))
+		// On multi-homed hosts whose policy forbids wildcard binds, the
+		// secure server can listen on an explicit address list instead of the
+		// single BindAddress.
+		secureAddresses := options.SecureBindAddresses
+		if len(secureAddresses) == 0 {
+			secureAddresses = []net.IP{options.BindAddress}
+		}
+		for _, address := range secureAddresses {
+			secureLocations = append(secureLocations, net.JoinHostPort(address.String(), strconv.Itoa(options.SecurePort)))
+		}
 	}
 	insecureLocation := net.JoinHostPort(options.InsecureBindAddress.String(), strconv.Itoa(options.InsecurePort))
 
 	var sem chan bool
 	if options.MaxRequestsInFlight > 0 {
 		sem = make(chan bool, options.MaxRequestsInFlight)
+		if s.enableMetrics {
+			metrics.RegisterInFlightGauge(func() int { return len(sem) })
+		}
+		if options.MaxInFlightSaturationTimeout > 0 {
+			if err := s.AddHealthzCheck("max-in-flight", maxInFlightCheck(sem, options.MaxInFlightSaturationTimeout)); err != nil {
+				glog.Errorf("Unable to register max-in-flight health check: %v", err)
+			}
+		}
+	}
+	var longRunningSem chan bool
+	if options.MaxLongRunningRequestsInFlight > 0 {
+		longRunningSem = make(chan bool, options.MaxLongRunningRequestsInFlight)
+	}
+
+	if s.compactionCoordinator != nil {
+		go s.compactionCoordinator.Run(util.NeverStop)
+	}
+
+	if s.identityLeaseController != nil {
+		go s.identityLeaseController.Run(util.NeverStop)
+	}
+
+	if s.standbyController != nil {
+		go s.standbyController.Run(util.NeverStop)
 	}
 
 	longRunningRE := regexp.MustCompile(options.LongRunningRequestRE)
-	longRunningTimeout := func(req *http.Request) (<-chan time.Time, string) {
+	requestTimeout := func(req *http.Request) time.Duration {
 		// TODO unify this with apiserver.MaxInFlightLimit
 		if longRunningRE.MatchString(req.URL.Path) || req.URL.Query().Get("watch") == "true" {
-			return nil, ""
+			return 0
 		}
-		return time.After(time.Minute), ""
+		// As documented on Config.MinRequestTimeout, each request gets a
+		// random timeout in [MinRequestTimeout, 2*MinRequestTimeout) so that
+		// retries of timed-out requests don't all expire in lockstep.
+		timeout := time.Minute
+		if s.MinRequestTimeout > 0 {
+			timeout = s.MinRequestTimeout + time.Duration(rand.Int63n(int64(s.MinRequestTimeout)))
+		}
+		return s.timeoutClamp.Clamp(timeout)
 	}
-
-	if secureLocation != "" {
-		handler := apiserver.TimeoutHandler(s.Handler, longRunningTimeout)
-		secureServer := &http.Server{
-			Addr:           secureLocation,
-			Handler:        apiserver.MaxInFlightLimit(sem, longRunningRE, apiserver.RecoverPanics(handler)),
-			MaxHeaderBytes: 1 << 20,
-			TLSConfig: &tls.Config{
-				// Change default from SSLv3 to TLSv1.0 (because of POODLE vulnerability)
-				MinVersion: tls.VersionTLS10,
-			},
+	longRunningTimeout := func(req *http.Request) (<-chan time.Time, string) {
+		if timeout := requestTimeout(req); timeout > 0 {
+			return time.After(timeout), ""
 		}
-
-		if len(options.ClientCAFile) > 0 {
-			clientCAs, err := util.CertPoolFromFile(options.ClientCAFile)
-			if err != nil {
-				glog.Fatalf("Unable to load client CA file: %v", err)
-			}
-			// Populate PeerCertificates in requests, but don't reject connections without certificates
-			// This allows certificates to be validated by authenticators, while still allowing other auth types
-			secureServer.TLSConfig.ClientAuth = tls.RequestClientCert
-			// Specify allowed CAs for client certificates
-			secureServer.TLSConfig.ClientCAs = clientCAs
+		// Proactively close long-running requests after MaxWatchDuration so
+		// clients re-establish them and pick up fresh routing (e.g. after a
+		// rolling upgrade) instead of hanging on a stale connection.
+		if options.MaxWatchDuration > 0 {
+			return time.After(options.MaxWatchDuration), ""
 		}
+		return nil, ""
+	}
+	// Let the deadline wrapper installed in init propagate the same timeout
+	// into request contexts, so storage calls are cancelled along with the
+	// HTTP response.
+	s.requestTimeoutFunc = requestTimeout
 
-		glog.Infof("Serving securely on %s", secureLocation)
+	if len(secureLocations) > 0 {
 		if options.TLSCertFile == "" && options.TLSPrivateKeyFile == "" {
 			options.TLSCertFile = path.Join(options.CertDirectory, "apiserver.crt")
 			options.TLSPrivateKeyFile = path.Join(options.CertDirectory, "apiserver.key")
@@ -590,6 +2054,8 @@ func (s *GenericAPIServer) Run(options *ServerRunOptions) {
 			alternateDNS := []string{"kubernetes.default.svc", "kubernetes.default", "kubernetes"}
 			// It would be nice to set a fqdn subject alt name, but only the kubelets know, the apiserver is clueless
 			// alternateDNS = append(alternateDNS, "kubernetes.default.svc.CLUSTER.DNS.NAME")
+			alternateIPs = append(alternateIPs, options.ExtraCertSANsIP...)
+			alternateDNS = append(alternateDNS, options.ExtraCertSANsDNS...)
 			if err := util.GenerateSelfSignedCert(s.ClusterIP.String(), options.TLSCertFile, options.TLSPrivateKeyFile, alternateIPs, alternateDNS); err != nil {
 				glog.Errorf("Unable to generate self signed cert: %v", err)
 			} else {
@@ -597,37 +2063,283 @@ func (s *GenericAPIServer) Run(options *ServerRunOptions) {
 			}
 		}
 
+		handler := apiserver.TimeoutHandler(s.Handler, longRunningTimeout)
+		if s.emitTimeoutHeader {
+			handler = apiserver.WithTimeoutHeader(handler, requestTimeout)
+		}
+		if s.emitFairnessHeaders {
+			handler = apiserver.WithFairnessHeaders(handler, longRunningRE)
+		}
+		if s.enableCompression {
+			handler = apiserver.WithCompression(handler, longRunningRE)
+		}
+		if options.MaxRequestBodyBytes > 0 {
+			handler = apiserver.WithRequestBodyLimit(handler, options.MaxRequestBodyBytes, longRunningRE)
+		}
+		// Track in-flight requests by type so Drain can wait for short
+		// requests before watches during shutdown.
+		handler = apiserver.WithRequestDraining(handler, longRunningRE, &s.requestDrainer)
+		if s.enableTLSNegotiationMetrics {
+			handler = apiserver.WithTLSNegotiationMetrics(handler)
+		}
+		// If an HTTP/2 client coalesces connections across hostnames, it may send
+		// us a request for a host our certificate doesn't serve; answer 421 so it
+		// reconnects rather than getting the wrong identity.
+		handler = apiserver.WithMisdirectedRequestCheck(handler, servedCertificateNames(options))
+		// Change default from SSLv3 to TLSv1.0 (because of POODLE vulnerability)
+		minVersion, err := tlsMinVersion(options.TLSMinVersion)
+		if err != nil {
+			glog.Fatalf("Invalid TLSMinVersion: %v", err)
+		}
+		cipherSuites, err := tlsCipherSuiteIDs(options.TLSCipherSuites)
+		if err != nil {
+			glog.Fatalf("Invalid TLSCipherSuites: %v", err)
+		}
+		tlsConfig := &tls.Config{
+			MinVersion:   minVersion,
+			CipherSuites: cipherSuites,
+			NextProtos:   tlsNextProtos(s.tlsNextProtos),
+		}
+		if len(options.ClientCAFile) > 0 {
+			// ClientCAFile may name several comma-separated files; their
+			// certificates are merged into one pool.
+			clientCAs, err := clientCAPool(options.ClientCAFile)
+			if err != nil {
+				glog.Fatalf("Unable to load client CA file: %v", err)
+			}
+			// Populate PeerCertificates in requests, but don't reject connections without certificates
+			// This allows certificates to be validated by authenticators, while still allowing other auth types
+			tlsConfig.ClientAuth = tls.RequestClientCert
+			// Specify allowed CAs for client certificates
+			tlsConfig.ClientCAs = clientCAs
+		}
+
+		secureHandler := apiserver.MaxInFlightLimitByClass(sem, longRunningSem, longRunningRE, apiserver.RecoverPanics(handler))
+
+		// err == systemd.SdNotifyNoSocket when not running on a systemd system
+		if err := systemd.SdNotify("READY=1\n"); err != nil && err != systemd.SdNotifyNoSocket {
+			glog.Errorf("Unable to send systemd daemon successful start message: %v\n", err)
+		}
+
+		// One listener per secure location, all serving the same handler.
+		for _, secureLocation := range secureLocations {
+			secureServer := &http.Server{
+				Addr:           secureLocation,
+				Handler:        secureHandler,
+				MaxHeaderBytes: 1 << 20,
+				TLSConfig:      tlsConfig,
+			}
+
+			if !options.EnableHTTP2 {
+				// An empty, non-nil TLSNextProto map suppresses the automatic
+				// HTTP/2 upgrade, so clients fall back to HTTP/1.1 even if ALPN
+				// advertised h2.
+				secureServer.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+			}
+
+			glog.Infof("Serving securely on %s", secureLocation)
+
+			go func(secureServer *http.Server, secureLocation string) {
+				defer util.HandleCrash()
+				retryInterval := options.SecureServeRetryInterval
+				if retryInterval == 0 {
+					retryInterval = defaultSecureServeRetryInterval
+				}
+				failures := 0
+				for {
+					err := listenAndServeTLS(secureServer, options.TLSCertFile, options.TLSPrivateKeyFile, s.connectionKeepAlivePeriod, s.maxConnectionsPerIP, options.HTTPReadTimeout, options.HTTPWriteTimeout, options.SNICertKeys, s.trackSecureListener)
+					if s.secureServingStopped() {
+						glog.Infof("Stopped serving securely on %s", secureLocation)
+						return
+					}
+					if err != nil {
+						failures++
+						if failures >= maxSecureServeFailures {
+							// A persistently failing bind (e.g. a misconfigured
+							// cert) will not fix itself; let a supervisor restart
+							// the process instead of spinning forever.
+							glog.Fatalf("Unable to listen for secure on %s after %d attempts (%v); giving up.", secureLocation, failures, err)
+						}
+						glog.Errorf("Unable to listen for secure on %s (%v); will try again in %v.", secureLocation, err, retryInterval)
+					}
+					time.Sleep(retryInterval)
+					// Back off between consecutive failures, capped so a long
+					// outage still retries regularly.
+					retryInterval *= 2
+					if retryInterval > maxSecureServeRetryInterval {
+						retryInterval = maxSecureServeRetryInterval
+					}
+				}
+			}(secureServer, secureLocation)
+		}
+	} else {
+		// err == systemd.SdNotifyNoSocket when not running on a systemd system
+		if err := systemd.SdNotify("READY=1\n"); err != nil && err != systemd.SdNotifyNoSocket {
+			glog.Errorf("Unable to send systemd daemon successful start message: %v\n", err)
+		}
+	}
+
+	// A dedicated health listener gives load balancers an app-level health
+	// signal: the port is only bound now that the server is ready to serve,
+	// and every response exercises the HTTP stack and storage reachability,
+	// so a TCP-accept probe can no longer mask a broken server.
+	if options.HealthPort != 0 {
+		healthMux := http.NewServeMux()
+		checks := []healthz.HealthzChecker{healthz.PingHealthz}
+		if len(s.storageBackends) > 0 {
+			checks = append(checks, healthz.NamedCheck("etcd", func(req *http.Request) error {
+				return s.checkStorageBackends()
+			}))
+		}
+		healthz.InstallHandler(healthMux, checks...)
+		healthLocation := net.JoinHostPort(options.BindAddress.String(), strconv.Itoa(options.HealthPort))
+		healthServer := &http.Server{
+			Addr:           healthLocation,
+			Handler:        healthMux,
+			MaxHeaderBytes: 1 << 20,
+		}
+		glog.Infof("Serving health checks on %s", healthLocation)
 		go func() {
 			defer util.HandleCrash()
 			for {
-				// err == systemd.SdNotifyNoSocket when not running on a systemd system
-				if err := systemd.SdNotify("READY=1\n"); err != nil && err != systemd.SdNotifyNoSocket {
-					glog.Errorf("Unable to send systemd daemon successful start message: %v\n", err)
+				if err := healthServer.ListenAndServe(); err != nil {
+					glog.Errorf("Unable to listen for health checks (%v); will try again.", err)
 				}
-				if err := secureServer.ListenAndServeTLS(options.TLSCertFile, options.TLSPrivateKeyFile); err != nil {
-					glog.Errorf("Unable to listen for secure (%v); will try again.", err)
+				time.Sleep(15 * time.Second)
+			}
+		}()
+	}
+
+	handler := apiserver.TimeoutHandler(s.InsecureHandler, longRunningTimeout)
+	if s.emitTimeoutHeader {
+		handler = apiserver.WithTimeoutHeader(handler, requestTimeout)
+	}
+	if s.emitFairnessHeaders {
+		handler = apiserver.WithFairnessHeaders(handler, longRunningRE)
+	}
+	if s.enableCompression {
+		handler = apiserver.WithCompression(handler, longRunningRE)
+	}
+	if options.MaxRequestBodyBytes > 0 {
+		handler = apiserver.WithRequestBodyLimit(handler, options.MaxRequestBodyBytes, longRunningRE)
+	}
+
+	// A read-only listener exposes the insecure handler to networks that must
+	// never mutate state (e.g. monitoring); everything but reads is rejected.
+	if s.readOnlyPort != 0 {
+		readOnlyLocation := net.JoinHostPort(options.InsecureBindAddress.String(), strconv.Itoa(s.readOnlyPort))
+		readOnlyServer := &http.Server{
+			Addr:           readOnlyLocation,
+			Handler:        apiserver.WithReadOnly(apiserver.RecoverPanics(handler), s.NewRequestInfoResolver()),
+			MaxHeaderBytes: 1 << 20,
+		}
+		glog.Infof("Serving read-only on %s", readOnlyLocation)
+		go func() {
+			defer util.HandleCrash()
+			for {
+				if err := readOnlyServer.ListenAndServe(); err != nil {
+					glog.Errorf("Unable to listen for read-only (%v); will try again.", err)
 				}
 				time.Sleep(15 * time.Second)
 			}
 		}()
-	} else {
-		// err == systemd.SdNotifyNoSocket when not running on a systemd system
-		if err := systemd.SdNotify("READY=1\n"); err != nil && err != systemd.SdNotifyNoSocket {
-			glog.Errorf("Unable to send systemd daemon successful start message: %v\n", err)
+	}
+
+	// Additionally serve the insecure handler on a local Unix socket, so
+	// sidecars can reach it without plaintext being exposed on any network
+	// interface.
+	if len(options.InsecureUnixSocket) > 0 {
+		socketPath := options.InsecureUnixSocket
+		// Remove a stale socket left behind by an unclean shutdown; listening
+		// fails if the path already exists.
+		if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+			glog.Fatalf("Unable to remove stale socket %s: %v", socketPath, err)
+		}
+		ln, err := net.Listen("unix", socketPath)
+		if err != nil {
+			glog.Fatalf("Unable to listen on socket %s: %v", socketPath, err)
+		}
+		if err := os.Chmod(socketPath, 0600); err != nil {
+			glog.Fatalf("Unable to restrict permissions on socket %s: %v", socketPath, err)
 		}
+		s.insecureUnixListener = ln
+		s.insecureUnixSocketPath = socketPath
+		unixServer := &http.Server{
+			Handler:        apiserver.RecoverPanics(handler),
+			MaxHeaderBytes: 1 << 20,
+		}
+		glog.Infof("Serving insecurely on unix socket %s", socketPath)
+		go func() {
+			defer util.HandleCrash()
+			if err := unixServer.Serve(ln); err != nil {
+				glog.Errorf("Stopped serving on unix socket %s: %v", socketPath, err)
+			}
+		}()
 	}
 
-	handler := apiserver.TimeoutHandler(s.InsecureHandler, longRunningTimeout)
 	http := &http.Server{
 		Addr:           insecureLocation,
 		Handler:        apiserver.RecoverPanics(handler),
 		MaxHeaderBytes: 1 << 20,
 	}
 	glog.Infof("Serving insecurely on %s", insecureLocation)
+	if s.maxConnectionsPerIP > 0 || options.HTTPReadTimeout > 0 || options.HTTPWriteTimeout > 0 {
+		ln, err := net.Listen("tcp", insecureLocation)
+		if err != nil {
+			glog.Fatalf("Unable to listen for insecure: %v", err)
+		}
+		inner := ln
+		if s.maxConnectionsPerIP > 0 {
+			inner = newPerIPLimitListener(inner, s.maxConnectionsPerIP)
+		}
+		glog.Fatal(http.Serve(newTimeoutListener(inner, options.HTTPReadTimeout, options.HTTPWriteTimeout)))
+	}
 	glog.Fatal(http.ListenAndServe())
 }
 
-func (s *GenericAPIServer) installAPIGroup(apiGroupInfo *APIGroupInfo) error {
+// servedCertificateNames returns the lowercased host names the secure server
+// can present a certificate for: the DNS names of the default serving
+// certificate, plus for each SNI certificate the configured host and the DNS
+// names of the certificate itself. Hosts served via SNI must not be rejected
+// by the misdirected-request check.
+func servedCertificateNames(options *ServerRunOptions) sets.String {
+	names := certificateDNSNames(options.TLSCertFile)
+	for _, certKey := range options.SNICertKeys {
+		tokens := strings.SplitN(certKey, ":", 3)
+		if len(tokens) != 3 {
+			// sniCertificates rejects the malformed entry at startup.
+			continue
+		}
+		names.Insert(strings.ToLower(tokens[0]))
+		names = names.Union(certificateDNSNames(tokens[1]))
+	}
+	return names
+}
+
+// certificateDNSNames returns the lowercased DNS names the certificate in the
+// given file is valid for. An unreadable or unparseable file yields an empty
+// set, which disables the SNI mismatch check.
+func certificateDNSNames(certFile string) sets.String {
+	names := sets.NewString()
+	pemBlock, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return names
+	}
+	block, _ := pem.Decode(pemBlock)
+	if block == nil {
+		return names
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return names
+	}
+	for _, name := range cert.DNSNames {
+		names.Insert(strings.ToLower(name))
+	}
+	return names
+}
+
+func (s *GenericAPIServer) installAPIGroup(apiGroupInfo *APIGroupInfo) (skipped []string, err error) {
 	apiPrefix := s.APIGroupPrefix
 	if apiGroupInfo.IsLegacyGroup {
 		apiPrefix = s.APIPrefix
@@ -636,24 +2348,83 @@ func (s *GenericAPIServer) installAPIGroup(apiGroupInfo *APIGroupInfo) error {
 	// Install REST handlers for all the versions in this group.
 	apiVersions := []string{}
 	for _, groupVersion := range apiGroupInfo.GroupMeta.GroupVersions {
+		if apiGroupInfo.IsLegacyGroup && s.legacyVersionDisabled(apiPrefix, groupVersion.Version) {
+			glog.V(1).Infof("Skipping disabled legacy API version %q", groupVersion.Version)
+			continue
+		}
+		// Skip group versions whose path is already registered, so installing
+		// a group twice (e.g. a plugin registering the same extension group as
+		// core) does not panic inside go-restful.
+		gvPath := path.Join(apiPrefix, groupVersion.Group, groupVersion.Version)
+		if s.webServiceRegistered(gvPath) {
+			glog.Warningf("Skipping API group version %q: a web service is already registered at %s", groupVersion.String(), gvPath)
+			skipped = append(skipped, groupVersion.String())
+			continue
+		}
 		apiVersions = append(apiVersions, groupVersion.Version)
+		s.installedGroupVersions = append(s.installedGroupVersions, groupVersion.String())
 
 		apiGroupVersion, err := s.getAPIGroupVersion(apiGroupInfo, groupVersion, apiPrefix)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if apiGroupInfo.OptionsExternalVersion != nil {
+			glog.Warningf("DEPRECATED: APIGroupInfo.OptionsExternalVersion is set for group %q; it will be removed when https://github.com/kubernetes/kubernetes/issues/19018 is fixed", apiGroupInfo.GroupMeta.GroupVersion.Group)
 			apiGroupVersion.OptionsExternalVersion = apiGroupInfo.OptionsExternalVersion
 		}
+		if deprecation, ok := apiGroupInfo.DeprecatedVersions[groupVersion.Version]; ok {
+			apiGroupVersion.Deprecation = &deprecation
+		}
 
 		if err := apiGroupVersion.InstallREST(s.HandlerContainer); err != nil {
-			return fmt.Errorf("Unable to setup API %v: %v", apiGroupInfo, err)
+			return nil, fmt.Errorf("Unable to setup API %v: %v", apiGroupInfo, err)
+		}
+
+		// Remember the installed web service so the group version can be
+		// disabled (and re-enabled) at runtime.
+		if s.groupVersionWebServices == nil {
+			s.groupVersionWebServices = map[string]*restful.WebService{}
+		}
+		for _, ws := range s.HandlerContainer.RegisteredWebServices() {
+			if ws.RootPath() == gvPath {
+				s.groupVersionWebServices[groupVersion.String()] = ws
+				break
+			}
 		}
+
+		// Record the installed resources and their verbs for /admin/apimatrix.
+		if s.apiResourceMatrix == nil {
+			s.apiResourceMatrix = map[string]map[string]map[string][]string{}
+		}
+		group := apiGroupInfo.GroupMeta.GroupVersion.Group
+		if s.apiResourceMatrix[group] == nil {
+			s.apiResourceMatrix[group] = map[string]map[string][]string{}
+		}
+		resources := map[string][]string{}
+		for resource, resourceStorage := range apiGroupInfo.VersionedResourcesStorageMap[groupVersion.Version] {
+			resources[strings.ToLower(resource)] = rest.StorageVerbs(resourceStorage)
+		}
+		s.apiResourceMatrix[group][groupVersion.Version] = resources
+	}
+	// If every version of the group was already registered, the discovery
+	// handler is in place as well; there is nothing left to install.
+	if len(apiVersions) == 0 && len(skipped) > 0 {
+		return skipped, nil
 	}
 	// Install the version handler.
 	if apiGroupInfo.IsLegacyGroup {
 		// Add a handler at /api to enumerate the supported api versions.
-		apiserver.AddApiWebService(s.HandlerContainer, apiPrefix, apiVersions)
+		if !s.webServiceRegistered(apiPrefix) {
+			apiserver.AddDynamicApiWebService(s.HandlerContainer, apiPrefix, func() []string {
+				enabled := []string{}
+				for _, version := range apiVersions {
+					if s.groupVersionEnabled(version) {
+						enabled = append(enabled, version)
+					}
+				}
+				return enabled
+			})
+		}
 	} else {
 		// Add a handler at /apis/<groupName> to enumerate all versions supported by this group.
 		apiVersionsForDiscovery := []unversioned.GroupVersionForDiscovery{}
@@ -672,12 +2443,99 @@ func (s *GenericAPIServer) installAPIGroup(apiGroupInfo *APIGroupInfo) error {
 			Versions:         apiVersionsForDiscovery,
 			PreferredVersion: preferedVersionForDiscovery,
 		}
-		apiserver.AddGroupWebService(s.HandlerContainer, apiPrefix+"/"+apiGroup.Name, apiGroup)
+		if !s.webServiceRegistered(apiPrefix + "/" + apiGroup.Name) {
+			apiserver.AddDynamicGroupWebService(s.HandlerContainer, apiPrefix+"/"+apiGroup.Name, func() unversioned.APIGroup {
+				enabled := apiGroup
+				enabled.Versions = []unversioned.GroupVersionForDiscovery{}
+				for _, version := range apiGroup.Versions {
+					if s.groupVersionEnabled(version.GroupVersion) {
+						enabled.Versions = append(enabled.Versions, version)
+					}
+				}
+				return enabled
+			})
+		}
 	}
 	apiserver.InstallServiceErrorHandler(s.HandlerContainer, s.NewRequestInfoResolver(), apiVersions)
+	return skipped, nil
+}
+
+// SetAPIGroupVersionEnabled enables or disables an installed API group
+// version at runtime by adding or removing its web service from the handler
+// container. The discovery endpoints reflect the change. It provides an
+// escape hatch for taking a misbehaving group version out of service without
+// restarting the apiserver.
+func (s *GenericAPIServer) SetAPIGroupVersionEnabled(groupVersion string, enabled bool) error {
+	s.runtimeGVLock.Lock()
+	defer s.runtimeGVLock.Unlock()
+	ws, ok := s.groupVersionWebServices[groupVersion]
+	if !ok {
+		return fmt.Errorf("group version %q is not installed", groupVersion)
+	}
+	if enabled {
+		if !s.disabledGroupVersions[groupVersion] {
+			return nil
+		}
+		s.HandlerContainer.Add(ws)
+		delete(s.disabledGroupVersions, groupVersion)
+		glog.Infof("Enabled API group version %q", groupVersion)
+		return nil
+	}
+	if s.disabledGroupVersions[groupVersion] {
+		return nil
+	}
+	if err := s.HandlerContainer.Remove(ws); err != nil {
+		return err
+	}
+	if s.disabledGroupVersions == nil {
+		s.disabledGroupVersions = map[string]bool{}
+	}
+	s.disabledGroupVersions[groupVersion] = true
+	glog.Infof("Disabled API group version %q", groupVersion)
 	return nil
 }
 
+// groupVersionEnabled reports whether an installed group version is currently
+// enabled, i.e. has not been removed via SetAPIGroupVersionEnabled.
+func (s *GenericAPIServer) groupVersionEnabled(groupVersion string) bool {
+	s.runtimeGVLock.Lock()
+	defer s.runtimeGVLock.Unlock()
+	return !s.disabledGroupVersions[groupVersion]
+}
+
+// ListedPaths returns the sorted union of the paths registered on the mux
+// and the root paths of the registered web services. It backs the "/" index
+// handler and lets embedders introspect the endpoints being served without
+// reaching into the server's internals.
+func (s *GenericAPIServer) ListedPaths() []string {
+	var paths []string
+	for _, ws := range s.HandlerContainer.RegisteredWebServices() {
+		paths = append(paths, ws.RootPath())
+	}
+	paths = append(paths, s.MuxHelper.RegisteredPaths...)
+	sort.Strings(paths)
+	return paths
+}
+
+// webServiceRegistered reports whether a web service is already registered at
+// the given root path in the handler container.
+func (s *GenericAPIServer) webServiceRegistered(rootPath string) bool {
+	for _, ws := range s.HandlerContainer.RegisteredWebServices() {
+		if ws.RootPath() == rootPath {
+			return true
+		}
+	}
+	return false
+}
+
+// legacyVersionDisabled reports whether a version of the legacy group has been
+// disabled via ApiGroupVersionOverrides. Overrides for the legacy group are
+// keyed the same way as for other groups, e.g. "api/v1".
+func (s *GenericAPIServer) legacyVersionDisabled(apiPrefix, version string) bool {
+	key := strings.TrimPrefix(apiPrefix, "/") + "/" + version
+	return s.ApiGroupVersionOverrides[key].Disable
+}
+
 func (s *GenericAPIServer) getAPIGroupVersion(apiGroupInfo *APIGroupInfo, groupVersion unversioned.GroupVersion, apiPrefix string) (*apiserver.APIGroupVersion, error) {
 	storage := make(map[string]rest.Storage)
 	for k, v := range apiGroupInfo.VersionedResourcesStorageMap[groupVersion.Version] {
@@ -686,6 +2544,9 @@ func (s *GenericAPIServer) getAPIGroupVersion(apiGroupInfo *APIGroupInfo, groupV
 	version, err := s.newAPIGroupVersion(apiGroupInfo.GroupMeta, groupVersion)
 	version.Root = apiPrefix
 	version.Storage = storage
+	if apiGroupInfo.AdmissionControl != nil {
+		version.Admit = apiGroupInfo.AdmissionControl
+	}
 	return version, err
 }
 
@@ -694,6 +2555,10 @@ func (s *GenericAPIServer) newAPIGroupVersion(groupMeta latest.GroupMeta, groupV
 	if err != nil {
 		return nil, err
 	}
+	codec := versionInterface.Codec
+	if s.jsonNumberAsString || len(s.jsonTimestampFormat) > 0 {
+		codec = runtime.JSONCompatCodec(codec, s.jsonNumberAsString, s.jsonTimestampFormat)
+	}
 	return &apiserver.APIGroupVersion{
 		RequestInfoResolver: s.NewRequestInfoResolver(),
 
@@ -704,12 +2569,21 @@ func (s *GenericAPIServer) newAPIGroupVersion(groupMeta latest.GroupMeta, groupV
 		GroupVersion: groupVersion,
 		Linker:       groupMeta.SelfLinker,
 		Mapper:       groupMeta.RESTMapper,
-		Codec:        versionInterface.Codec,
+		Codec:        codec,
 
 		Admit:   s.AdmissionControl,
 		Context: s.RequestContextMapper,
 
 		MinRequestTimeout: s.MinRequestTimeout,
+
+		CacheEnabled: s.enableWatchCache,
+
+		DefaultListLimit:   s.defaultListLimit,
+		ListLimitOverrides: s.listLimitOverrides,
+
+		EmitTimeoutHeader: s.emitTimeoutHeader,
+
+		StreamingDecode: s.streamingDecode,
 	}, nil
 }
 
@@ -718,23 +2592,47 @@ func (s *GenericAPIServer) newAPIGroupVersion(groupMeta latest.GroupMeta, groupV
 // register their own web services into the Kubernetes mux prior to initialization
 // of swagger, so that other resource types show up in the documentation.
 func (s *GenericAPIServer) InstallSwaggerAPI() {
-	hostAndPort := s.externalHost
-	protocol := "https://"
+	s.installSwaggerAPI(s.HandlerContainer.RegisteredWebServices())
+}
 
-	// TODO: this is kind of messed up, we should just pipe in the full URL from the outside, rather
-	// than guessing at it.
-	if len(s.externalHost) == 0 && s.ClusterIP != nil {
-		host := s.ClusterIP.String()
-		if s.PublicReadWritePort != 0 {
-			hostAndPort = net.JoinHostPort(host, strconv.Itoa(s.PublicReadWritePort))
+// InstallSwaggerAPIForGroups behaves like InstallSwaggerAPI but limits the
+// swagger spec to web services whose root path starts with one of the given
+// prefixes (e.g. "/api", "/apis/extensions"), so groups whose schema breaks
+// swagger clients can be left out of the documentation.
+func (s *GenericAPIServer) InstallSwaggerAPIForGroups(groups []string) {
+	webServices := []*restful.WebService{}
+	for _, ws := range s.HandlerContainer.RegisteredWebServices() {
+		for _, group := range groups {
+			if strings.HasPrefix(ws.RootPath(), group) {
+				webServices = append(webServices, ws)
+				break
+			}
+		}
+	}
+	s.installSwaggerAPI(webServices)
+}
+
+func (s *GenericAPIServer) installSwaggerAPI(webServices []*restful.WebService) {
+	webServicesUrl := s.swaggerWebServicesURL
+	if len(webServicesUrl) == 0 {
+		// No URL was configured, so guess one from the external host or the
+		// cluster IP. This is wrong behind a TLS-terminating proxy; set
+		// Config.SwaggerWebServicesURL there instead.
+		hostAndPort := s.externalHost
+		protocol := "https://"
+		if len(s.externalHost) == 0 && s.ClusterIP != nil {
+			host := s.ClusterIP.String()
+			if s.PublicReadWritePort != 0 {
+				hostAndPort = net.JoinHostPort(host, strconv.Itoa(s.PublicReadWritePort))
+			}
 		}
+		webServicesUrl = protocol + hostAndPort
 	}
-	webServicesUrl := protocol + hostAndPort
 
 	// Enable swagger UI and discovery API
 	swaggerConfig := swagger.Config{
 		WebServicesUrl:  webServicesUrl,
-		WebServices:     s.HandlerContainer.RegisteredWebServices(),
+		WebServices:     webServices,
 		ApiPath:         "/swaggerapi/",
 		SwaggerPath:     "/swaggerui/",
 		SwaggerFilePath: "/swagger-ui/",
@@ -18,15 +18,19 @@ package genericapiserver
 
 import (
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"k8s.io/kubernetes/pkg/admission"
 	"k8s.io/kubernetes/pkg/api"
 	"k8s.io/kubernetes/pkg/api/latest"
 	"k8s.io/kubernetes/pkg/api/rest"
+	"k8s.io/kubernetes/pkg/api/unversioned"
 	"k8s.io/kubernetes/pkg/apis/extensions"
 	"k8s.io/kubernetes/pkg/apiserver"
 	etcdtesting "k8s.io/kubernetes/pkg/storage/etcd/testing"
@@ -85,6 +89,44 @@ func TestNew(t *testing.T) {
 	assert.Equal(s.ProxyTransport.(*http.Transport).TLSClientConfig, config.ProxyTLSClientConfig)
 }
 
+// TestNewWithErrorConfigValidation verifies that invalid service IP
+// configuration is reported as an error instead of terminating the process.
+func TestNewWithErrorConfigValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	// ServiceReadWriteIP outside ServiceClusterIPRange.
+	config := Config{}
+	config.PublicAddress = net.ParseIP("192.168.10.4")
+	_, ipRange, _ := net.ParseCIDR("10.0.0.0/24")
+	config.ServiceClusterIPRange = ipRange
+	config.ServiceReadWriteIP = net.ParseIP("192.168.10.4")
+	_, err := NewWithError(&config)
+	assert.Error(err)
+
+	// ServiceClusterIPRange too small.
+	config = Config{}
+	config.PublicAddress = net.ParseIP("192.168.10.4")
+	_, ipRange, _ = net.ParseCIDR("10.0.0.0/31")
+	config.ServiceClusterIPRange = ipRange
+	_, err = NewWithError(&config)
+	assert.Error(err)
+}
+
+// TestSetDefaultsIPv6ServiceRange verifies that an IPv6 service cluster IP
+// range yields a service read-write IP within the range.
+func TestSetDefaultsIPv6ServiceRange(t *testing.T) {
+	assert := assert.New(t)
+
+	config := Config{}
+	config.PublicAddress = net.ParseIP("192.168.10.4")
+	_, ipRange, err := net.ParseCIDR("fd00::/64")
+	assert.NoError(err)
+	config.ServiceClusterIPRange = ipRange
+	assert.NoError(setDefaults(&config))
+	assert.True(config.ServiceReadWriteIP.Equal(net.ParseIP("fd00::1")),
+		"expected service read-write IP fd00::1, got %v", config.ServiceReadWriteIP)
+}
+
 // Verifies that AddGroupVersions works as expected.
 func TestInstallAPIGroups(t *testing.T) {
 	_, etcdserver, config, assert := setUp(t)
@@ -134,6 +176,130 @@ func TestInstallAPIGroups(t *testing.T) {
 	}
 }
 
+// Verifies that a legacy group version disabled via APIGroupVersionOverrides
+// is not installed and does not show up in the /api discovery document.
+func TestInstallAPIGroupsLegacyVersionDisabled(t *testing.T) {
+	_, etcdserver, config, assert := setUp(t)
+	defer etcdserver.Terminate(t)
+
+	config.ProxyDialer = func(network, addr string) (net.Conn, error) { return nil, nil }
+	config.ProxyTLSClientConfig = &tls.Config{}
+	config.APIPrefix = "/api"
+	config.APIGroupVersionOverrides = map[string]APIGroupVersionOverride{
+		"api/v1": {Disable: true},
+	}
+
+	s := New(&config)
+	apiGroupMeta := latest.GroupOrDie(api.GroupName)
+	apiGroupsInfo := []APIGroupInfo{
+		{
+			GroupMeta:                    *apiGroupMeta,
+			VersionedResourcesStorageMap: map[string]map[string]rest.Storage{},
+			IsLegacyGroup:                true,
+		},
+	}
+	s.InstallAPIGroups(apiGroupsInfo)
+
+	// TODO: Close() this server when fix #19254
+	server := httptest.NewServer(s.HandlerContainer.ServeMux)
+
+	// The disabled version should not be served.
+	resp, err := http.Get(server.URL + "/api/v1")
+	if assert.NoError(err) {
+		assert.Equal(http.StatusNotFound, resp.StatusCode)
+	}
+
+	// The /api discovery document should not list the disabled version.
+	resp, err = http.Get(server.URL + "/api")
+	if !assert.NoError(err) {
+		t.Fatalf("unexpected error getting /api: %v", err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !assert.NoError(err) {
+		t.Fatalf("unexpected error reading /api response: %v", err)
+	}
+	apiVersions := unversioned.APIVersions{}
+	if !assert.NoError(json.Unmarshal(body, &apiVersions)) {
+		t.Fatalf("unexpected error unmarshaling /api response: %s", string(body))
+	}
+	assert.NotContains(apiVersions.Versions, "v1")
+}
+
+// namedAdmission is a stub admission.Interface distinguishable by name.
+type namedAdmission struct {
+	name string
+}
+
+func (namedAdmission) Admit(a admission.Attributes) error  { return nil }
+func (namedAdmission) Handles(op admission.Operation) bool { return true }
+
+// TestPerGroupAdmissionControl verifies that a group-level admission chain
+// overrides the server-wide one, and that the server default is used when no
+// override is set.
+func TestPerGroupAdmissionControl(t *testing.T) {
+	_, etcdserver, config, assert := setUp(t)
+	defer etcdserver.Terminate(t)
+
+	serverWide := namedAdmission{name: "server-wide"}
+	groupOnly := namedAdmission{name: "group-only"}
+	config.AdmissionControl = serverWide
+
+	s := New(&config)
+	apiGroupMeta := latest.GroupOrDie(api.GroupName)
+	info := APIGroupInfo{
+		GroupMeta:                    *apiGroupMeta,
+		VersionedResourcesStorageMap: map[string]map[string]rest.Storage{},
+		IsLegacyGroup:                true,
+	}
+
+	version, err := s.getAPIGroupVersion(&info, apiGroupMeta.GroupVersion, "/api")
+	assert.NoError(err)
+	assert.Equal(serverWide, version.Admit)
+
+	info.AdmissionControl = groupOnly
+	version, err = s.getAPIGroupVersion(&info, apiGroupMeta.GroupVersion, "/api")
+	assert.NoError(err)
+	assert.Equal(groupOnly, version.Admit)
+}
+
+// Verifies that orderAPIGroups honors declared dependencies and rejects
+// cycles and missing dependencies.
+func TestOrderAPIGroups(t *testing.T) {
+	assert := assert.New(t)
+	group := func(name string, dependsOn ...string) APIGroupInfo {
+		return APIGroupInfo{
+			GroupMeta: latest.GroupMeta{GroupVersion: unversioned.GroupVersion{Group: name}},
+			DependsOn: dependsOn,
+		}
+	}
+	names := func(groups []APIGroupInfo) []string {
+		result := []string{}
+		for _, g := range groups {
+			result = append(result, g.GroupMeta.GroupVersion.Group)
+		}
+		return result
+	}
+
+	// Groups without dependencies keep their relative order.
+	ordered, err := orderAPIGroups([]APIGroupInfo{group("a"), group("b"), group("c")})
+	assert.NoError(err)
+	assert.Equal([]string{"a", "b", "c"}, names(ordered))
+
+	// Dependencies are installed first.
+	ordered, err = orderAPIGroups([]APIGroupInfo{group("a", "c"), group("b"), group("c", "b")})
+	assert.NoError(err)
+	assert.Equal([]string{"b", "c", "a"}, names(ordered))
+
+	// A missing dependency is an error.
+	_, err = orderAPIGroups([]APIGroupInfo{group("a", "nonexistent")})
+	assert.Error(err)
+
+	// A dependency cycle is an error.
+	_, err = orderAPIGroups([]APIGroupInfo{group("a", "b"), group("b", "a")})
+	assert.Error(err)
+}
+
 // TestNewHandlerContainer verifies that NewHandlerContainer uses the
 // mux provided
 func TestNewHandlerContainer(t *testing.T) {
@@ -206,3 +372,31 @@ func TestInstallSwaggerAPI(t *testing.T) {
 		assert.Equal("/swaggerapi/", ws[0].RootPath(), "SwaggerAPI did not install to the proper path. %s != /swaggerapi", ws[0].RootPath())
 	}
 }
+
+// TestBuildHandlerChainOverride verifies that a custom BuildHandlerChain is
+// used in place of the default and can reuse DefaultBuildHandlerChain as a
+// building block.
+func TestBuildHandlerChainOverride(t *testing.T) {
+	_, etcdserver, config, assert := setUp(t)
+	defer etcdserver.Terminate(t)
+
+	called := false
+	config.BuildHandlerChain = func(apiHandler http.Handler, c *Config) http.Handler {
+		called = true
+		inner := DefaultBuildHandlerChain(apiHandler, c)
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("X-Custom-Filter", "yes")
+			inner.ServeHTTP(w, req)
+		})
+	}
+	s := New(&config)
+	assert.True(called, "expected the custom BuildHandlerChain to be used")
+
+	server := httptest.NewServer(s.Handler)
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/healthz")
+	if assert.NoError(err) {
+		defer resp.Body.Close()
+		assert.Equal("yes", resp.Header.Get("X-Custom-Filter"))
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genericapiserver
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/storage"
+	"k8s.io/kubernetes/pkg/util"
+
+	"github.com/golang/glog"
+	"golang.org/x/net/context"
+)
+
+// identityLeasePrefix is the storage directory holding one lease object per
+// live apiserver instance.
+const identityLeasePrefix = "/apiserverleases/"
+
+// newInstanceID generates an identifier unique to this apiserver process,
+// stable for its lifetime: the hostname plus a random suffix so multiple
+// instances on one host stay distinguishable.
+func newInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s_%s", hostname, util.NewUUID())
+}
+
+// identityLeaseController publishes a heartbeat lease identifying this
+// apiserver instance so HA tooling can enumerate live instances. The lease
+// carries a TTL and is renewed well inside it, so it disappears shortly after
+// an instance stops renewing; on graceful shutdown it is removed immediately.
+type identityLeaseController struct {
+	storage    storage.Interface
+	instanceID string
+	ttl        time.Duration
+}
+
+// Run renews the lease until stopCh is closed, then removes it.
+func (c *identityLeaseController) Run(stopCh <-chan struct{}) {
+	// Renew at a third of the TTL so a single missed round does not expire
+	// the lease.
+	util.Until(c.renew, c.ttl/3, stopCh)
+	c.remove()
+}
+
+func (c *identityLeaseController) renew() {
+	ttl := uint64(c.ttl / time.Second)
+	if ttl == 0 {
+		ttl = 1
+	}
+	lease := &api.Endpoints{ObjectMeta: api.ObjectMeta{Name: c.instanceID}}
+	out := &api.Endpoints{}
+	if err := c.storage.Set(context.TODO(), identityLeasePrefix+c.instanceID, lease, out, ttl); err != nil {
+		glog.Errorf("Unable to renew apiserver identity lease: %v", err)
+	}
+}
+
+func (c *identityLeaseController) remove() {
+	out := &api.Endpoints{}
+	if err := c.storage.Delete(context.TODO(), identityLeasePrefix+c.instanceID, out); err != nil {
+		glog.Errorf("Unable to remove apiserver identity lease: %v", err)
+	}
+}
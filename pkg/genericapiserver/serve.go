@@ -0,0 +1,386 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genericapiserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/kubernetes/pkg/util"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// clientCAPool merges the certificates from a comma-separated list of PEM
+// files into a single pool, so client certificates issued by several
+// independent CAs can all be verified. Certificates that fail to load are
+// logged and skipped so one bad file does not block the remaining trust
+// anchors; an error is returned only when no certificate loads at all.
+func clientCAPool(clientCAFiles string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	total := 0
+	for _, file := range strings.Split(clientCAFiles, ",") {
+		file = strings.TrimSpace(file)
+		if len(file) == 0 {
+			continue
+		}
+		pemBlock, err := ioutil.ReadFile(file)
+		if err != nil {
+			glog.Warningf("Unable to read client CA file %s: %v", file, err)
+			continue
+		}
+		certs, err := util.CertsFromPEM(pemBlock)
+		if err != nil {
+			// Keep whatever did parse; one bad certificate should not drop
+			// the rest of the trust anchors.
+			glog.Warningf("Error parsing client CA file %s: %v", file, err)
+		}
+		for _, cert := range certs {
+			pool.AddCert(cert)
+		}
+		total += len(certs)
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("no client CA certificates loaded from %q", clientCAFiles)
+	}
+	return pool, nil
+}
+
+// tlsNextProtos returns the ALPN protocol list to advertise on the secure
+// server: the configured list, or HTTP/1.1 only when none was configured.
+func tlsNextProtos(configured []string) []string {
+	if len(configured) == 0 {
+		return []string{"http/1.1"}
+	}
+	return configured
+}
+
+// validateTLSNextProtos checks that a configured ALPN protocol list still
+// includes "http/1.1", so ordinary API clients can always negotiate.
+func validateTLSNextProtos(protos []string) error {
+	if len(protos) == 0 {
+		return nil
+	}
+	for _, proto := range protos {
+		if proto == "http/1.1" {
+			return nil
+		}
+	}
+	return fmt.Errorf("TLS next protocols %v must include \"http/1.1\"", protos)
+}
+
+// tlsVersions maps the human-readable TLS version names accepted in
+// configuration to the crypto/tls constants.
+var tlsVersions = map[string]uint16{
+	"VersionSSL30": tls.VersionSSL30,
+	"VersionTLS10": tls.VersionTLS10,
+	"VersionTLS11": tls.VersionTLS11,
+	"VersionTLS12": tls.VersionTLS12,
+}
+
+// tlsCipherSuites maps the human-readable cipher suite names accepted in
+// configuration to the crypto/tls constants.
+var tlsCipherSuites = map[string]uint16{
+	"TLS_RSA_WITH_RC4_128_SHA":                tls.TLS_RSA_WITH_RC4_128_SHA,
+	"TLS_RSA_WITH_3DES_EDE_CBC_SHA":           tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_CBC_SHA":            tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_RSA_WITH_AES_256_CBC_SHA":            tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_RC4_128_SHA":        tls.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA":    tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA":    tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_RC4_128_SHA":          tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA,
+	"TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA":     tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA":      tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA":      tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256":   tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256": tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384":   tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384": tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+}
+
+// tlsMinVersion maps a configured TLS version name to its constant. An empty
+// name keeps the current TLS 1.0 default for backward compatibility.
+func tlsMinVersion(name string) (uint16, error) {
+	if name == "" {
+		return tls.VersionTLS10, nil
+	}
+	version, ok := tlsVersions[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q", name)
+	}
+	return version, nil
+}
+
+// tlsCipherSuiteIDs maps configured cipher suite names to their constants,
+// rejecting unknown names. An empty list keeps Go's default cipher suites.
+func tlsCipherSuiteIDs(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := tlsCipherSuites[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown TLS cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// sniCertificates parses host:certfile:keyfile triples and loads the named
+// key pairs, keyed by lowercased host name.
+func sniCertificates(certKeys []string) (map[string]tls.Certificate, error) {
+	certs := map[string]tls.Certificate{}
+	for _, entry := range certKeys {
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid SNI cert entry %q, expected host:certfile:keyfile", entry)
+		}
+		cert, err := tls.LoadX509KeyPair(parts[1], parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("unable to load SNI certificate for %q: %v", parts[0], err)
+		}
+		certs[strings.ToLower(parts[0])] = cert
+	}
+	return certs, nil
+}
+
+// sniGetCertificate returns a GetCertificate callback that selects a
+// certificate by the TLS server name the client indicated. Names without a
+// match return nil so the TLS stack falls back to the default certificate.
+func sniGetCertificate(certs map[string]tls.Certificate) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return func(clientHello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if cert, ok := certs[strings.ToLower(clientHello.ServerName)]; ok {
+			return &cert, nil
+		}
+		return nil, nil
+	}
+}
+
+// keepAliveListener applies a TCP keepalive period to accepted connections so
+// that peers silently dropped by NATs or load balancers are detected and the
+// connection is closed promptly, even when no HTTP traffic flows. This keeps
+// long-lived watch connections healthy through intermediaries.
+type keepAliveListener struct {
+	*net.TCPListener
+	period time.Duration
+}
+
+func (l keepAliveListener) Accept() (net.Conn, error) {
+	conn, err := l.AcceptTCP()
+	if err != nil {
+		return nil, err
+	}
+	conn.SetKeepAlive(true)
+	conn.SetKeepAlivePeriod(l.period)
+	return conn, nil
+}
+
+// timeoutListener applies I/O deadlines compatible with long-running
+// requests: a read deadline covers only the initial request (a slowloris
+// defense) and each response write must complete within writeTimeout.
+// Neither bounds the total lifetime of a connection, so watches stay open as
+// long as the client keeps reading.
+type timeoutListener struct {
+	net.Listener
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+}
+
+func newTimeoutListener(inner net.Listener, readTimeout, writeTimeout time.Duration) net.Listener {
+	if readTimeout == 0 && writeTimeout == 0 {
+		return inner
+	}
+	return &timeoutListener{Listener: inner, readTimeout: readTimeout, writeTimeout: writeTimeout}
+}
+
+func (l *timeoutListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if l.readTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(l.readTimeout))
+	}
+	return &timeoutConn{Conn: conn, readTimeout: l.readTimeout, writeTimeout: l.writeTimeout}, nil
+}
+
+// timeoutConn clears the initial read deadline on the first response write
+// (by then the request has been read), and arms a fresh write deadline for
+// every write. A client that never finishes its request or stops reading the
+// response is disconnected, while an idle watch, which has no pending I/O,
+// is left alone.
+type timeoutConn struct {
+	net.Conn
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	wroteOnce    bool
+}
+
+func (c *timeoutConn) Write(b []byte) (int, error) {
+	if !c.wroteOnce {
+		c.wroteOnce = true
+		if c.readTimeout > 0 {
+			c.Conn.SetReadDeadline(time.Time{})
+		}
+	}
+	if c.writeTimeout > 0 {
+		c.Conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	}
+	return c.Conn.Write(b)
+}
+
+var perIPConnectionRejections = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "apiserver_connection_rejections_per_ip",
+		Help: "Counter of connections rejected because their source IP was at its concurrent connection limit.",
+	},
+)
+
+var registerPerIPConnectionMetric sync.Once
+
+// perIPLimitListener caps concurrent connections per source IP, so one
+// misbehaving client cannot exhaust the server's connection capacity for
+// everyone. The limit applies to the direct peer address; behind a proxy all
+// connections share the proxy's IP, so this only makes sense for directly
+// exposed listeners.
+type perIPLimitListener struct {
+	net.Listener
+	max int
+
+	lock  sync.Mutex
+	conns map[string]int
+}
+
+func newPerIPLimitListener(inner net.Listener, max int) net.Listener {
+	registerPerIPConnectionMetric.Do(func() {
+		prometheus.MustRegister(perIPConnectionRejections)
+	})
+	return &perIPLimitListener{Listener: inner, max: max, conns: map[string]int{}}
+}
+
+func (l *perIPLimitListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		ip, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+		if err != nil {
+			// Not a host:port address; nothing sensible to count against.
+			return conn, nil
+		}
+		l.lock.Lock()
+		if l.conns[ip] >= l.max {
+			l.lock.Unlock()
+			perIPConnectionRejections.Inc()
+			glog.V(2).Infof("Rejecting connection from %s: at the per-IP limit of %d", ip, l.max)
+			conn.Close()
+			continue
+		}
+		l.conns[ip]++
+		l.lock.Unlock()
+		return &perIPTrackedConn{Conn: conn, listener: l, ip: ip}, nil
+	}
+}
+
+func (l *perIPLimitListener) release(ip string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.conns[ip] <= 1 {
+		delete(l.conns, ip)
+	} else {
+		l.conns[ip]--
+	}
+}
+
+// perIPTrackedConn releases its slot in the per-IP count exactly once when
+// closed, however many times Close is called.
+type perIPTrackedConn struct {
+	net.Conn
+	listener *perIPLimitListener
+	ip       string
+	once     sync.Once
+}
+
+func (c *perIPTrackedConn) Close() error {
+	c.once.Do(func() { c.listener.release(c.ip) })
+	return c.Conn.Close()
+}
+
+// listenAndServeTLS mirrors http.Server.ListenAndServeTLS, but applies the
+// given keepalive period, per-IP connection limit, and I/O timeouts to
+// accepted connections when non-zero. registerListener, when non-nil, is
+// called with the listener before serving starts so the caller can close it
+// to stop serving.
+func listenAndServeTLS(server *http.Server, certFile, keyFile string, keepAlivePeriod time.Duration, maxConnectionsPerIP int, readTimeout, writeTimeout time.Duration, sniCertKeys []string, registerListener func(net.Listener)) error {
+	if registerListener == nil && keepAlivePeriod == 0 && maxConnectionsPerIP == 0 && readTimeout == 0 && writeTimeout == 0 && len(sniCertKeys) == 0 {
+		return server.ListenAndServeTLS(certFile, keyFile)
+	}
+
+	addr := server.Addr
+	if addr == "" {
+		addr = ":https"
+	}
+	config := &tls.Config{}
+	if server.TLSConfig != nil {
+		*config = *server.TLSConfig
+	}
+	if len(config.NextProtos) == 0 {
+		config.NextProtos = []string{"http/1.1"}
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	config.Certificates = []tls.Certificate{cert}
+	if len(sniCertKeys) > 0 {
+		certs, err := sniCertificates(sniCertKeys)
+		if err != nil {
+			return err
+		}
+		config.GetCertificate = sniGetCertificate(certs)
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	inner := ln
+	if keepAlivePeriod > 0 {
+		inner = keepAliveListener{ln.(*net.TCPListener), keepAlivePeriod}
+	}
+	if maxConnectionsPerIP > 0 {
+		inner = newPerIPLimitListener(inner, maxConnectionsPerIP)
+	}
+	inner = newTimeoutListener(inner, readTimeout, writeTimeout)
+	tlsListener := tls.NewListener(inner, config)
+	if registerListener != nil {
+		registerListener(tlsListener)
+	}
+	defer tlsListener.Close()
+	return server.Serve(tlsListener)
+}
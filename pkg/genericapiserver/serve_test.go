@@ -0,0 +1,145 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genericapiserver
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/kubernetes/pkg/apiserver"
+	"k8s.io/kubernetes/pkg/util"
+)
+
+func TestTLSNextProtos(t *testing.T) {
+	testCases := []struct {
+		configured []string
+		expected   []string
+		valid      bool
+	}{
+		// Empty config falls back to HTTP/1.1 only.
+		{nil, []string{"http/1.1"}, true},
+		{[]string{}, []string{"http/1.1"}, true},
+		// Custom lists are used verbatim, preserving preference order.
+		{[]string{"grpc", "http/1.1"}, []string{"grpc", "http/1.1"}, true},
+		{[]string{"http/1.1"}, []string{"http/1.1"}, true},
+		// Lists that drop http/1.1 are rejected.
+		{[]string{"grpc"}, nil, false},
+	}
+	for i, tc := range testCases {
+		err := validateTLSNextProtos(tc.configured)
+		if tc.valid && err != nil {
+			t.Errorf("%d: unexpected error: %v", i, err)
+			continue
+		}
+		if !tc.valid {
+			if err == nil {
+				t.Errorf("%d: expected error for %v", i, tc.configured)
+			}
+			continue
+		}
+		if protos := tlsNextProtos(tc.configured); !reflect.DeepEqual(protos, tc.expected) {
+			t.Errorf("%d: expected %v, got %v", i, tc.expected, protos)
+		}
+	}
+}
+
+func TestTLSMinVersion(t *testing.T) {
+	// Empty keeps the TLS 1.0 default.
+	if version, err := tlsMinVersion(""); err != nil || version != tls.VersionTLS10 {
+		t.Errorf("expected TLS 1.0 default, got %v, %v", version, err)
+	}
+	if version, err := tlsMinVersion("VersionTLS12"); err != nil || version != tls.VersionTLS12 {
+		t.Errorf("expected TLS 1.2, got %v, %v", version, err)
+	}
+	if _, err := tlsMinVersion("TLSv1.2"); err == nil {
+		t.Errorf("expected error for unknown version name")
+	}
+}
+
+func TestTLSCipherSuiteIDs(t *testing.T) {
+	// Empty keeps Go's defaults.
+	if ids, err := tlsCipherSuiteIDs(nil); err != nil || ids != nil {
+		t.Errorf("expected nil for empty list, got %v, %v", ids, err)
+	}
+	ids, err := tlsCipherSuiteIDs([]string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected %v, got %v", expected, ids)
+	}
+	if _, err := tlsCipherSuiteIDs([]string{"TLS_BOGUS"}); err == nil {
+		t.Errorf("expected error for unknown cipher suite name")
+	}
+}
+
+func TestServedCertificateNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "served-cert-names")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	defaultCert := filepath.Join(dir, "default.crt")
+	defaultKey := filepath.Join(dir, "default.key")
+	if err := util.GenerateSelfSignedCert("kubernetes.default", defaultCert, defaultKey, nil, []string{"kubernetes"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sniCert := filepath.Join(dir, "sni.crt")
+	sniKey := filepath.Join(dir, "sni.key")
+	if err := util.GenerateSelfSignedCert("api.example.com", sniCert, sniKey, nil, []string{"alt.example.com"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	options := NewServerRunOptions()
+	options.TLSCertFile = defaultCert
+	options.SNICertKeys = []string{"API.EXAMPLE.COM:" + sniCert + ":" + sniKey, "malformed"}
+
+	names := servedCertificateNames(options)
+	for _, expected := range []string{"kubernetes.default", "kubernetes", "api.example.com", "alt.example.com"} {
+		if !names.Has(expected) {
+			t.Errorf("expected %q in served names, got %v", expected, names.List())
+		}
+	}
+	if names.Has("malformed") {
+		t.Errorf("malformed SNI entry should be skipped, got %v", names.List())
+	}
+
+	// A host served via an SNI certificate must pass the misdirected-request
+	// check even though the default certificate does not cover it.
+	handler := apiserver.WithMisdirectedRequestCheck(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), names)
+	w := httptest.NewRecorder()
+	req := &http.Request{
+		Method: "GET",
+		URL:    &url.URL{Path: "/api"},
+		TLS:    &tls.ConnectionState{ServerName: "api.example.com"},
+	}
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected an SNI-served host to pass the misdirected-request check, got %d", w.Code)
+	}
+}
@@ -18,34 +18,131 @@ package genericapiserver
 
 import (
 	"net"
+	"time"
 )
 
 const (
 	// TODO: This can be tightened up. It still matches objects named watch or proxy.
 	defaultLongRunningRequestRE = "(/|^)((watch|proxy)(/|$)|(logs?|portforward|exec|attach)/?$)"
+
+	defaultSecureServeRetryInterval = 15 * time.Second
+
+	// Generous bounds on request reads and response writes; they exist to
+	// shed wedged connections, not to police slow-but-live clients.
+	defaultHTTPReadTimeout  = 5 * time.Minute
+	defaultHTTPWriteTimeout = time.Minute
 )
 
 // ServerRunOptions contains the options while running a generic api server.
 type ServerRunOptions struct {
-	BindAddress          net.IP
-	CertDirectory        string
-	ClientCAFile         string
-	InsecureBindAddress  net.IP
-	InsecurePort         int
+	BindAddress   net.IP
+	CertDirectory string
+	// ClientCAFile names one or more comma-separated PEM files whose
+	// certificates are merged into the client CA pool.
+	ClientCAFile string
+	// ExtraCertSANsDNS and ExtraCertSANsIP are appended to the subject
+	// alternate names of the generated self-signed serving cert, so clients
+	// reaching the server through additional names (e.g. an external load
+	// balancer) can still verify it. They have no effect when TLSCertFile and
+	// TLSPrivateKeyFile are provided explicitly.
+	ExtraCertSANsDNS    []string
+	ExtraCertSANsIP     []net.IP
+	InsecureBindAddress net.IP
+	InsecurePort        int
+	// InsecureUnixSocket additionally serves the insecure handler on a Unix
+	// domain socket at the given path, restricted to mode 0600, so local
+	// sidecars can reach it without plaintext being exposed on any network
+	// interface. Empty disables the socket.
+	InsecureUnixSocket   string
 	LongRunningRequestRE string
 	MaxRequestsInFlight  int
-	SecurePort           int
-	TLSCertFile          string
-	TLSPrivateKeyFile    string
+	// MaxLongRunningRequestsInFlight caps concurrent long-running requests
+	// (those matching LongRunningRequestRE) with a semaphore of their own,
+	// so runaway watch clients cannot consume unbounded connections. Zero
+	// leaves long-running requests unlimited, as before.
+	MaxLongRunningRequestsInFlight int
+	// MaxInFlightSaturationTimeout makes the /healthz endpoint fail once the
+	// in-flight request semaphore has been fully saturated for longer than
+	// this duration, so a wedged server stops passing health checks even
+	// though its listener still accepts connections. Zero disables the check.
+	MaxInFlightSaturationTimeout time.Duration
+	// MaxRequestBodyBytes rejects requests whose body exceeds the limit with
+	// a 413, complementing the fixed header size limit. Long-running
+	// requests matched by LongRunningRequestRE are exempt. Zero leaves
+	// bodies unlimited.
+	MaxRequestBodyBytes int64
+	// MaxWatchDuration bounds how long a watch (or any long-running request)
+	// may stay open before the server closes it, prompting the client to
+	// re-establish the connection. Zero leaves watches open indefinitely, as
+	// before.
+	MaxWatchDuration time.Duration
+	// SNICertKeys lists host:certfile:keyfile triples; the secure server
+	// presents the named certificate to clients whose TLS server name matches
+	// the host, and falls back to the TLSCertFile certificate otherwise. This
+	// allows serving different certificates under different hostnames.
+	SNICertKeys []string
+	// SecureBindAddresses, when non-empty, replaces BindAddress for the
+	// secure server: one listener is created per address, all serving the
+	// same handler and closed together on shutdown. Useful on multi-homed
+	// hosts whose security policy forbids wildcard binds. The insecure
+	// server keeps using InsecureBindAddress.
+	SecureBindAddresses []net.IP
+	SecurePort          int
+	TLSCertFile         string
+	TLSPrivateKeyFile   string
+
+	// TLSMinVersion names the minimum TLS version the secure server accepts
+	// (e.g. "VersionTLS12"). Empty keeps the current TLS 1.0 default.
+	TLSMinVersion string
+	// TLSCipherSuites names the cipher suites the secure server offers (e.g.
+	// "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"). Empty keeps Go's defaults.
+	// Unknown names are rejected at startup.
+	TLSCipherSuites []string
+
+	// EnableHTTP2 controls whether the secure server takes part in HTTP/2
+	// protocol negotiation. Some proxies mishandle HTTP/2; setting this to
+	// false forces HTTP/1.1 by installing an empty TLSNextProto map, which
+	// disables the automatic upgrade regardless of what ALPN advertises.
+	EnableHTTP2 bool
+
+	// HTTPReadTimeout bounds how long a client may take to deliver its
+	// request; the deadline is lifted once the response starts, so
+	// long-running requests are unaffected. HTTPWriteTimeout bounds each
+	// individual response write, disconnecting clients that stop reading
+	// without bounding the lifetime of a watch. Zero disables the respective
+	// timeout.
+	HTTPReadTimeout  time.Duration
+	HTTPWriteTimeout time.Duration
+
+	// ProfilingBlockRate is passed to runtime.SetBlockProfileRate so the
+	// /debug/pprof/block profile records goroutine blocking events. It only
+	// takes effect when profiling is enabled; zero leaves block profiling
+	// off, as before.
+	ProfilingBlockRate int
+
+	// HealthPort, if non-zero, serves health checks on a dedicated listener
+	// that is only bound once the server is ready to serve. Load balancers
+	// probing it get an app-level health signal instead of mere TCP-accept
+	// port-open detection. Zero disables the listener.
+	HealthPort int
+
+	// SecureServeRetryInterval is the initial delay between attempts to bind
+	// the secure port; consecutive failures back off exponentially from it.
+	SecureServeRetryInterval time.Duration
 }
 
 func NewServerRunOptions() *ServerRunOptions {
 	return &ServerRunOptions{
 		BindAddress:          net.ParseIP("0.0.0.0"),
 		CertDirectory:        "/var/run/kubernetes",
+		EnableHTTP2:          true,
+		HTTPReadTimeout:      defaultHTTPReadTimeout,
+		HTTPWriteTimeout:     defaultHTTPWriteTimeout,
 		InsecureBindAddress:  net.ParseIP("127.0.0.1"),
 		InsecurePort:         8080,
 		LongRunningRequestRE: defaultLongRunningRequestRE,
 		SecurePort:           6443,
+
+		SecureServeRetryInterval: defaultSecureServeRetryInterval,
 	}
 }
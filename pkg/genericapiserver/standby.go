@@ -0,0 +1,182 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package genericapiserver
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/runtime"
+	"k8s.io/kubernetes/pkg/storage"
+	"k8s.io/kubernetes/pkg/util"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+// standbyLeaderKey is the storage key holding the warm-standby leader lease.
+const standbyLeaderKey = "/apiserverleader"
+
+// defaultStandbyLeaseTTL is how long the leader lease lives without renewal.
+const defaultStandbyLeaseTTL = 15 * time.Second
+
+var standbyRoleGauge = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "apiserver_standby_active",
+		Help: "1 while this apiserver holds the leader lease and serves writes, 0 while it is a warm standby.",
+	},
+)
+
+var registerStandbyRoleMetric sync.Once
+
+// standbyController runs a storage-based leader election for warm standby
+// mode. The instance holding the leader lease serves writes and runs the
+// callbacks registered via OnActive; every other instance keeps serving
+// reads but rejects writes with a hint naming the current leader, giving an
+// active/passive HA topology behind a shared load balancer.
+type standbyController struct {
+	storage    storage.Interface
+	instanceID string
+	ttl        time.Duration
+
+	lock   sync.RWMutex
+	active bool
+	leader string
+
+	// onActive callbacks run once, the first time leadership is acquired,
+	// to start the controllers deferred while on standby.
+	onActive []func()
+	started  bool
+}
+
+func newStandbyController(store storage.Interface, instanceID string, ttl time.Duration) *standbyController {
+	registerStandbyRoleMetric.Do(func() {
+		prometheus.MustRegister(standbyRoleGauge)
+	})
+	if ttl == 0 {
+		ttl = defaultStandbyLeaseTTL
+	}
+	return &standbyController{storage: store, instanceID: instanceID, ttl: ttl}
+}
+
+// Active reports whether this instance currently holds leadership, along
+// with the identity of the current leader (as far as it is known) for client
+// hints.
+func (c *standbyController) Active() (bool, string) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.active, c.leader
+}
+
+// OnActive registers a callback run once, the first time leadership is
+// acquired. Must be called before Run.
+func (c *standbyController) OnActive(fn func()) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onActive = append(c.onActive, fn)
+}
+
+// Run attempts to acquire and then keep the leader lease until stopCh is
+// closed. The lease carries a TTL, so a crashed leader is superseded shortly
+// after it stops renewing.
+func (c *standbyController) Run(stopCh <-chan struct{}) {
+	// Renew at a third of the TTL so a single missed round does not expire
+	// the lease.
+	util.Until(c.tick, c.ttl/3, stopCh)
+}
+
+func (c *standbyController) tick() {
+	ttl := uint64(c.ttl / time.Second)
+	if ttl == 0 {
+		ttl = 1
+	}
+	lease := &api.Endpoints{ObjectMeta: api.ObjectMeta{Name: c.instanceID}}
+	out := &api.Endpoints{}
+	if active, _ := c.Active(); active {
+		// Renew with a compare-and-swap on the lease owner: after a partition
+		// or pause longer than the TTL another instance may have acquired the
+		// lease, and an unconditional write would overwrite it and leave two
+		// active instances. Step down instead when the lease is not ours.
+		newLeader := ""
+		err := c.storage.GuaranteedUpdate(context.TODO(), standbyLeaderKey, &api.Endpoints{}, false,
+			func(input runtime.Object, res storage.ResponseMeta) (runtime.Object, *uint64, error) {
+				current, ok := input.(*api.Endpoints)
+				if !ok {
+					return nil, nil, fmt.Errorf("unexpected lease object type %T", input)
+				}
+				if current.Name != c.instanceID {
+					newLeader = current.Name
+					return nil, nil, fmt.Errorf("lease is held by %q", current.Name)
+				}
+				return lease, &ttl, nil
+			})
+		if err != nil {
+			glog.Errorf("Unable to renew leader lease, stepping down to standby: %v", err)
+			c.setRole(false, newLeader)
+		}
+		return
+	}
+	// Try to take the lease; Create fails while another holder's lease is
+	// still live.
+	if err := c.storage.Create(context.TODO(), standbyLeaderKey, lease, out, ttl); err == nil {
+		glog.Infof("Acquired leader lease; switching from standby to active")
+		c.setRole(true, c.instanceID)
+		c.runOnActive()
+		return
+	}
+	// Still standby; record the current leader for client hints.
+	current := &api.Endpoints{}
+	if err := c.storage.Get(context.TODO(), standbyLeaderKey, current, true); err == nil {
+		c.setRole(false, current.Name)
+	}
+}
+
+func (c *standbyController) setRole(active bool, leader string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.active = active
+	c.leader = leader
+	if active {
+		standbyRoleGauge.Set(1)
+	} else {
+		standbyRoleGauge.Set(0)
+	}
+}
+
+// runOnActive starts the deferred callbacks exactly once. Leadership lost
+// later does not stop them: controllers are expected to hold their own leases
+// where that matters, and an apiserver that lost its lease will shortly be
+// superseded anyway.
+func (c *standbyController) runOnActive() {
+	c.lock.Lock()
+	callbacks := c.onActive
+	alreadyStarted := c.started
+	c.started = true
+	c.lock.Unlock()
+	if alreadyStarted {
+		return
+	}
+	for _, fn := range callbacks {
+		go func(fn func()) {
+			defer util.HandleCrash()
+			fn()
+		}(fn)
+	}
+}
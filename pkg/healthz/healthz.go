@@ -69,6 +69,19 @@ func InstallHandler(mux mux, checks ...HealthzChecker) {
 	}
 }
 
+// DynamicHandler returns a handler that serves the checks returned by f at
+// the time of each request, so checks may be registered after the handler is
+// installed. An empty set of checks behaves like PingHealthz.
+func DynamicHandler(f func() []HealthzChecker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checks := f()
+		if len(checks) == 0 {
+			checks = []HealthzChecker{PingHealthz}
+		}
+		handleRootHealthz(checks...).ServeHTTP(w, r)
+	})
+}
+
 // mux is an interface describing the methods InstallHandler requires.
 type mux interface {
 	Handle(pattern string, handler http.Handler)
@@ -41,6 +41,32 @@ func TestInstallHandler(t *testing.T) {
 	}
 }
 
+func TestDynamicHandler(t *testing.T) {
+	var checks []HealthzChecker
+	handler := DynamicHandler(func() []HealthzChecker { return checks })
+
+	// No checks registered yet: behaves like PingHealthz.
+	req, err := http.NewRequest("GET", "http://example.com/healthz", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected %v, got %v", http.StatusOK, w.Code)
+	}
+
+	// A check registered after installation is picked up.
+	checks = append(checks, NamedCheck("bad", func(_ *http.Request) error {
+		return errors.New("this will fail")
+	}))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected %v, got %v", http.StatusInternalServerError, w.Code)
+	}
+}
+
 func TestMulitipleChecks(t *testing.T) {
 	tests := []struct {
 		path             string
@@ -33,7 +33,6 @@ import (
 	"k8s.io/kubernetes/pkg/apis/extensions"
 	"k8s.io/kubernetes/pkg/apiserver"
 	"k8s.io/kubernetes/pkg/genericapiserver"
-	"k8s.io/kubernetes/pkg/healthz"
 	kubeletclient "k8s.io/kubernetes/pkg/kubelet/client"
 	"k8s.io/kubernetes/pkg/master/ports"
 	"k8s.io/kubernetes/pkg/registry/componentstatus"
@@ -161,10 +160,11 @@ func (m *Master) InstallAPIs(c *Config) {
 	apiGroupsInfo := []genericapiserver.APIGroupInfo{}
 
 	// Run the tunnel.
-	healthzChecks := []healthz.HealthzChecker{}
 	if m.tunneler != nil {
 		m.tunneler.Run(m.getNodeAddresses)
-		healthzChecks = append(healthzChecks, healthz.NamedCheck("SSH Tunnel Check", m.IsTunnelSyncHealthy))
+		if err := m.AddHealthzCheck("SSH Tunnel Check", func() error { return m.IsTunnelSyncHealthy(nil) }); err != nil {
+			glog.Errorf("Unable to register SSH tunnel health check: %v", err)
+		}
 		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
 			Name: "apiserver_proxy_tunnel_sync_latency_secs",
 			Help: "The time since the last successful synchronization of the SSH tunnels for proxy requests.",
@@ -172,7 +172,7 @@ func (m *Master) InstallAPIs(c *Config) {
 	}
 
 	// TODO(nikhiljindal): Refactor generic parts of support services (like /versions) to genericapiserver.
-	apiserver.InstallSupport(m.MuxHelper, m.RootWebService, c.EnableProfiling, healthzChecks...)
+	apiserver.InstallSupport(m.MuxHelper, m.RootWebService, c.EnableProfiling)
 
 	// Install v1 unless disabled.
 	if !m.ApiGroupVersionOverrides["api/v1"].Disable {
@@ -231,9 +231,13 @@ func (m *Master) InstallAPIs(c *Config) {
 		}
 		allGroups = append(allGroups, group)
 	}
-	if err := m.InstallAPIGroups(apiGroupsInfo); err != nil {
+	skipped, err := m.InstallAPIGroups(apiGroupsInfo)
+	if err != nil {
 		glog.Fatalf("Error in registering group versions: %v", err)
 	}
+	for _, groupVersion := range skipped {
+		glog.Warningf("Group version %q was already registered and has been skipped", groupVersion)
+	}
 
 	// This should be done after all groups are registered
 	// TODO: replace the hardcoded "apis".
@@ -34,3 +34,17 @@ func StorageWithCacher(
 		storageInterface, capacity, etcdstorage.APIObjectVersioner{},
 		objectType, resourcePrefix, namespaceScoped, newListFunc)
 }
+
+// Creates a cacher that resumes watches whose resourceVersion has expired
+// from its current state instead of failing them with 410 Gone.
+func StorageWithRelistingCacher(
+	storageInterface storage.Interface,
+	capacity int,
+	objectType runtime.Object,
+	resourcePrefix string,
+	namespaceScoped bool,
+	newListFunc func() runtime.Object) storage.Interface {
+	return storage.NewRelistingCacher(
+		storageInterface, capacity, etcdstorage.APIObjectVersioner{},
+		objectType, resourcePrefix, namespaceScoped, newListFunc)
+}
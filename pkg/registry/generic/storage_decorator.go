@@ -17,6 +17,8 @@ limitations under the License.
 package generic
 
 import (
+	"time"
+
 	"k8s.io/kubernetes/pkg/runtime"
 	"k8s.io/kubernetes/pkg/storage"
 )
@@ -31,6 +33,21 @@ type StorageDecorator func(
 	namespaceScoped bool,
 	newListFunc func() runtime.Object) storage.Interface
 
+// StorageWithReadRetries returns a StorageDecorator that retries transient
+// failures of idempotent reads on the decorated storage up to 'attempts'
+// times, waiting 'backoff' between tries. Mutations are never retried.
+func StorageWithReadRetries(attempts int, backoff time.Duration) StorageDecorator {
+	return func(
+		storageInterface storage.Interface,
+		capacity int,
+		objectType runtime.Object,
+		resourcePrefix string,
+		namespaceScoped bool,
+		newListFunc func() runtime.Object) storage.Interface {
+		return storage.NewReadRetryingStorage(storageInterface, attempts, backoff)
+	}
+}
+
 // Returns given 'storageInterface' without any decoration.
 func UndecoratedStorage(
 	storageInterface storage.Interface,
@@ -19,6 +19,7 @@ package ipallocator
 import (
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
 	"net"
 
@@ -197,19 +198,20 @@ func (r *Range) contains(ip net.IP) (bool, int) {
 	return true, offset
 }
 
-// bigForIP creates a big.Int based on the provided net.IP
+// bigForIP creates a big.Int based on the provided net.IP. The 16-byte
+// representation is used for both families so that offset arithmetic is
+// uniform and leading zero bytes survive the round trip.
 func bigForIP(ip net.IP) *big.Int {
-	b := ip.To4()
-	if b == nil {
-		b = ip.To16()
-	}
-	return big.NewInt(0).SetBytes(b)
+	return big.NewInt(0).SetBytes(ip.To16())
 }
 
 // addIPOffset adds the provided integer offset to a base big.Int representing a
 // net.IP
 func addIPOffset(base *big.Int, offset int) net.IP {
-	return net.IP(big.NewInt(0).Add(base, big.NewInt(int64(offset))).Bytes())
+	r := big.NewInt(0).Add(base, big.NewInt(int64(offset))).Bytes()
+	// big.Int.Bytes strips leading zeros; pad back to a full address.
+	r = append(make([]byte, 16), r...)
+	return net.IP(r[len(r)-16:])
 }
 
 // calculateIPOffset calculates the integer offset of ip from base such that
@@ -218,11 +220,13 @@ func calculateIPOffset(base *big.Int, ip net.IP) int {
 	return int(big.NewInt(0).Sub(bigForIP(ip), base).Int64())
 }
 
-// RangeSize returns the size of a range in valid addresses.
+// RangeSize returns the size of a range in valid addresses, capped at the
+// largest size an allocator can address. Large ranges (such as an IPv6 /64)
+// report the cap rather than overflowing.
 func RangeSize(subnet *net.IPNet) int64 {
 	ones, bits := subnet.Mask.Size()
 	if (bits - ones) >= 31 {
-		panic("masks greater than 31 bits are not supported")
+		return math.MaxInt32
 	}
 	max := int64(1) << uint(bits-ones)
 	return max
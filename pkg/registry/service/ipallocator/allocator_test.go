@@ -17,6 +17,7 @@ limitations under the License.
 package ipallocator
 
 import (
+	"math"
 	"net"
 	"testing"
 
@@ -155,6 +156,9 @@ func TestRangeSize(t *testing.T) {
 		"192.168.1.0/24": 256,
 		"192.168.1.0/32": 1,
 		"192.168.1.0/31": 2,
+		// Sizes too large to address are capped rather than overflowed.
+		"fd00::/64":  math.MaxInt32,
+		"fd00::/120": 256,
 	}
 	for k, v := range testCases {
 		_, cidr, err := net.ParseCIDR(k)
@@ -167,6 +171,27 @@ func TestRangeSize(t *testing.T) {
 	}
 }
 
+func TestGetIndexedIP(t *testing.T) {
+	testCases := map[string]string{
+		"192.168.1.0/24": "192.168.1.1",
+		"fd00::/64":      "fd00::1",
+	}
+	for k, v := range testCases {
+		_, cidr, err := net.ParseCIDR(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ip, err := GetIndexedIP(cidr, 1)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", k, err)
+			continue
+		}
+		if !ip.Equal(net.ParseIP(v)) {
+			t.Errorf("%s should have an indexed IP of %s, got %s", k, v, ip)
+		}
+	}
+}
+
 func TestSnapshot(t *testing.T) {
 	_, cidr, err := net.ParseCIDR("192.168.1.0/24")
 	if err != nil {
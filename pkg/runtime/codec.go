@@ -17,7 +17,10 @@ limitations under the License.
 package runtime
 
 import (
+	"bytes"
+	"encoding/json"
 	"io"
+	"time"
 
 	"k8s.io/kubernetes/pkg/api/unversioned"
 	"k8s.io/kubernetes/pkg/util/yaml"
@@ -79,6 +82,85 @@ func (c yamlCodec) DecodeInto(data []byte, obj Object) error {
 	return c.Codec.DecodeInto(data, obj)
 }
 
+// MaxSafeJSONInteger is the largest integer (2^53-1) that survives a round
+// trip through an IEEE 754 double, which is how JavaScript represents JSON
+// numbers.
+const MaxSafeJSONInteger = 1<<53 - 1
+
+// jsonCompatCodec rewrites encoded JSON for interoperability with clients
+// that cannot represent the full int64 range or expect a specific timestamp
+// layout.
+type jsonCompatCodec struct {
+	// a Codec for JSON
+	Codec
+	numberAsString  bool
+	timestampFormat string
+}
+
+// JSONCompatCodec wraps a codec that produces JSON so that, when
+// numberAsString is set, encoded integers whose magnitude exceeds
+// MaxSafeJSONInteger are emitted as strings, and, when timestampFormat is
+// non-empty, RFC3339 timestamps are re-rendered in that layout. Decoding is
+// unchanged.
+func JSONCompatCodec(codec Codec, numberAsString bool, timestampFormat string) Codec {
+	return &jsonCompatCodec{Codec: codec, numberAsString: numberAsString, timestampFormat: timestampFormat}
+}
+
+func (c *jsonCompatCodec) Encode(obj Object) ([]byte, error) {
+	data, err := c.Codec.Encode(obj)
+	if err != nil {
+		return nil, err
+	}
+	return c.rewrite(data)
+}
+
+func (c *jsonCompatCodec) EncodeToStream(obj Object, stream io.Writer) error {
+	data, err := c.Encode(obj)
+	if err != nil {
+		return err
+	}
+	_, err = stream.Write(data)
+	return err
+}
+
+func (c *jsonCompatCodec) rewrite(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		// Not a JSON document; leave the output alone.
+		return data, nil
+	}
+	return json.Marshal(c.rewriteValue(value))
+}
+
+func (c *jsonCompatCodec) rewriteValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, element := range v {
+			v[key] = c.rewriteValue(element)
+		}
+	case []interface{}:
+		for i, element := range v {
+			v[i] = c.rewriteValue(element)
+		}
+	case json.Number:
+		if !c.numberAsString {
+			return v
+		}
+		if n, err := v.Int64(); err == nil && (n > MaxSafeJSONInteger || n < -MaxSafeJSONInteger) {
+			return v.String()
+		}
+	case string:
+		if len(c.timestampFormat) > 0 {
+			if t, err := time.Parse(time.RFC3339, v); err == nil {
+				return t.Format(c.timestampFormat)
+			}
+		}
+	}
+	return value
+}
+
 // EncodeOrDie is a version of Encode which will panic instead of returning an error. For tests.
 func EncodeOrDie(codec Codec, obj Object) string {
 	bytes, err := Encode(codec, obj)
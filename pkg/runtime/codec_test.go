@@ -0,0 +1,53 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runtime
+
+import (
+	"testing"
+)
+
+func TestJSONCompatCodecRewrite(t *testing.T) {
+	testCases := []struct {
+		numberAsString  bool
+		timestampFormat string
+		in              string
+		expect          string
+	}{
+		// Small integers are untouched.
+		{true, "", `{"a":1,"b":-42}`, `{"a":1,"b":-42}`},
+		// Integers beyond the IEEE 754 safe range become strings.
+		{true, "", `{"a":9007199254740993}`, `{"a":"9007199254740993"}`},
+		{true, "", `{"a":[-9007199254740993]}`, `{"a":["-9007199254740993"]}`},
+		// Disabled, large integers pass through.
+		{false, "", `{"a":9007199254740993}`, `{"a":9007199254740993}`},
+		// RFC3339 timestamps are re-rendered in the configured layout.
+		{false, "2006-01-02", `{"t":"2016-03-04T05:06:07Z"}`, `{"t":"2016-03-04"}`},
+		// Non-timestamp strings are untouched.
+		{false, "2006-01-02", `{"t":"not a time"}`, `{"t":"not a time"}`},
+	}
+	for i, testCase := range testCases {
+		codec := &jsonCompatCodec{numberAsString: testCase.numberAsString, timestampFormat: testCase.timestampFormat}
+		out, err := codec.rewrite([]byte(testCase.in))
+		if err != nil {
+			t.Errorf("%d: unexpected error: %v", i, err)
+			continue
+		}
+		if string(out) != testCase.expect {
+			t.Errorf("%d: expected %s, got %s", i, testCase.expect, string(out))
+		}
+	}
+}
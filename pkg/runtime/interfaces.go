@@ -53,6 +53,14 @@ type Encoder interface {
 	// EncodeParameters(obj Object) (url.Values, error)
 }
 
+// StreamingDecoder is an optional interface for codecs that can decode an
+// object directly from a stream, without the caller buffering the full
+// serialized form in memory first. Decoding behaves like
+// DecodeIntoWithSpecifiedVersionKind.
+type StreamingDecoder interface {
+	DecodeFromStreamInto(stream io.Reader, obj Object, groupVersionKind unversioned.GroupVersionKind) error
+}
+
 // ObjectCodec represents the common mechanisms for converting to and from a particular
 // binary representation of an object.
 // TODO: Remove this interface - it is used only in CodecFor() method.
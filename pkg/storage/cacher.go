@@ -25,10 +25,12 @@ import (
 	"time"
 
 	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/errors"
 	"k8s.io/kubernetes/pkg/api/meta"
 	"k8s.io/kubernetes/pkg/client/cache"
 	"k8s.io/kubernetes/pkg/conversion"
 	"k8s.io/kubernetes/pkg/runtime"
+	"k8s.io/kubernetes/pkg/storage/metrics"
 	"k8s.io/kubernetes/pkg/util"
 	"k8s.io/kubernetes/pkg/watch"
 
@@ -58,6 +60,11 @@ type CacherConfig struct {
 	// NewList is a function that creates new empty object storing a list of
 	// objects of type Type.
 	NewListFunc func() runtime.Object
+
+	// RelistOnExpiredWatch resumes watches whose resourceVersion has fallen
+	// out of the cache window from the cache's current state (as a fresh
+	// list) instead of failing them with 410 Gone.
+	RelistOnExpiredWatch bool
 }
 
 // Cacher is responsible for serving WATCH and LIST requests for a given
@@ -99,6 +106,12 @@ type Cacher struct {
 	// keyFunc is used to get a key in the underyling storage for a given object.
 	keyFunc func(runtime.Object) (string, error)
 
+	// resource labels watch metrics emitted for this cache.
+	resource string
+
+	// relistOnExpired resumes too-old watches from current state.
+	relistOnExpired bool
+
 	// Handling graceful termination.
 	stopLock sync.RWMutex
 	stopped  bool
@@ -117,6 +130,34 @@ func NewCacher(
 	resourcePrefix string,
 	namespaceScoped bool,
 	newListFunc func() runtime.Object) Interface {
+	config := newCacherConfig(storage, capacity, versioner, objectType, resourcePrefix, namespaceScoped, newListFunc)
+	return NewCacherFromConfig(config)
+}
+
+// NewRelistingCacher is NewCacher with RelistOnExpiredWatch enabled: watches
+// whose resourceVersion has fallen out of the cache window are resumed from
+// the cache's current state instead of failing with 410 Gone.
+func NewRelistingCacher(
+	storage Interface,
+	capacity int,
+	versioner Versioner,
+	objectType runtime.Object,
+	resourcePrefix string,
+	namespaceScoped bool,
+	newListFunc func() runtime.Object) Interface {
+	config := newCacherConfig(storage, capacity, versioner, objectType, resourcePrefix, namespaceScoped, newListFunc)
+	config.RelistOnExpiredWatch = true
+	return NewCacherFromConfig(config)
+}
+
+func newCacherConfig(
+	storage Interface,
+	capacity int,
+	versioner Versioner,
+	objectType runtime.Object,
+	resourcePrefix string,
+	namespaceScoped bool,
+	newListFunc func() runtime.Object) CacherConfig {
 	config := CacherConfig{
 		CacheCapacity:  capacity,
 		Storage:        storage,
@@ -134,26 +175,29 @@ func NewCacher(
 			return NoNamespaceKeyFunc(resourcePrefix, obj)
 		}
 	}
-	return NewCacherFromConfig(config)
+	return config
 }
 
 // Create a new Cacher responsible from service WATCH and LIST requests from its
 // internal cache and updating its cache in the background based on the given
 // configuration.
 func NewCacherFromConfig(config CacherConfig) *Cacher {
+	metrics.Register()
 	watchCache := newWatchCache(config.CacheCapacity)
 	listerWatcher := newCacherListerWatcher(config.Storage, config.ResourcePrefix, config.NewListFunc)
 
 	cacher := &Cacher{
-		usable:     sync.RWMutex{},
-		storage:    config.Storage,
-		watchCache: watchCache,
-		reflector:  cache.NewReflector(listerWatcher, config.Type, watchCache, 0),
-		watcherIdx: 0,
-		watchers:   make(map[int]*cacheWatcher),
-		versioner:  config.Versioner,
-		keyFunc:    config.KeyFunc,
-		stopped:    false,
+		usable:          sync.RWMutex{},
+		storage:         config.Storage,
+		watchCache:      watchCache,
+		reflector:       cache.NewReflector(listerWatcher, config.Type, watchCache, 0),
+		resource:        strings.Trim(config.ResourcePrefix, "/"),
+		relistOnExpired: config.RelistOnExpiredWatch,
+		watcherIdx:      0,
+		watchers:        make(map[int]*cacheWatcher),
+		versioner:       config.Versioner,
+		keyFunc:         config.KeyFunc,
+		stopped:         false,
 		// We need to (potentially) stop both:
 		// - util.Until go-routine
 		// - reflector.ListAndWatch
@@ -250,12 +294,21 @@ func (c *Cacher) Watch(ctx context.Context, key string, resourceVersion string,
 	defer c.watchCache.RUnlock()
 	initEvents, err := c.watchCache.GetAllEventsSinceThreadUnsafe(watchRV)
 	if err != nil {
-		return nil, err
+		if !c.relistOnExpired || !errors.IsResourceExpired(err) {
+			return nil, err
+		}
+		// The requested resourceVersion has fallen out of the cache window.
+		// Resume from the cache's current state, delivered as a fresh list of
+		// Added events, instead of failing the watch.
+		initEvents, err = c.watchCache.GetAllEventsSinceThreadUnsafe(0)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	c.Lock()
 	defer c.Unlock()
-	watcher := newCacheWatcher(initEvents, filterFunction(key, c.keyFunc, filter), forgetWatcher(c, c.watcherIdx))
+	watcher := newCacheWatcher(initEvents, filterFunction(key, c.keyFunc, filter), forgetWatcher(c, c.watcherIdx), c.resource)
 	c.watchers[c.watcherIdx] = watcher
 	c.watcherIdx++
 	return watcher, nil
@@ -446,20 +499,22 @@ func (lw *cacherListerWatcher) Watch(options api.ListOptions) (watch.Interface,
 // cacherWatch implements watch.Interface
 type cacheWatcher struct {
 	sync.Mutex
-	input   chan watchCacheEvent
-	result  chan watch.Event
-	filter  FilterFunc
-	stopped bool
-	forget  func(bool)
+	input    chan watchCacheEvent
+	result   chan watch.Event
+	filter   FilterFunc
+	stopped  bool
+	forget   func(bool)
+	resource string
 }
 
-func newCacheWatcher(initEvents []watchCacheEvent, filter FilterFunc, forget func(bool)) *cacheWatcher {
+func newCacheWatcher(initEvents []watchCacheEvent, filter FilterFunc, forget func(bool), resource string) *cacheWatcher {
 	watcher := &cacheWatcher{
-		input:   make(chan watchCacheEvent, 10),
-		result:  make(chan watch.Event, 10),
-		filter:  filter,
-		stopped: false,
-		forget:  forget,
+		input:    make(chan watchCacheEvent, 10),
+		result:   make(chan watch.Event, 10),
+		filter:   filter,
+		stopped:  false,
+		forget:   forget,
+		resource: resource,
 	}
 	go watcher.process(initEvents)
 	return watcher
@@ -492,6 +547,7 @@ func (c *cacheWatcher) add(event watchCacheEvent) {
 		// This means that we couldn't send event to that watcher.
 		// Since we don't want to blockin on it infinitely,
 		// we simply terminate it.
+		metrics.RecordSlowWatchTermination(c.resource)
 		c.forget(false)
 		c.stop()
 	}
@@ -520,6 +576,13 @@ func (c *cacheWatcher) sendWatchCacheEvent(event watchCacheEvent) {
 		c.result <- watch.Event{Type: watch.Modified, Object: object}
 	case !curObjPasses && oldObjPasses:
 		c.result <- watch.Event{Type: watch.Deleted, Object: object}
+	default:
+		return
+	}
+	// Initial events replayed from the cache have no receive time; only
+	// events observed live measure delivery latency.
+	if !event.ReceivedAt.IsZero() {
+		metrics.RecordWatchEventDelivery(c.resource, event.ReceivedAt)
 	}
 }
 
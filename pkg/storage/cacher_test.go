@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/errors"
 	"k8s.io/kubernetes/pkg/api/meta"
 	"k8s.io/kubernetes/pkg/api/testapi"
 	apitesting "k8s.io/kubernetes/pkg/api/testing"
@@ -60,6 +61,21 @@ func newTestCacher(s storage.Interface) *storage.Cacher {
 	return storage.NewCacherFromConfig(config)
 }
 
+func newTestRelistingCacher(s storage.Interface) *storage.Cacher {
+	prefix := "pods"
+	config := storage.CacherConfig{
+		CacheCapacity:        10,
+		Storage:              s,
+		Versioner:            etcdstorage.APIObjectVersioner{},
+		Type:                 &api.Pod{},
+		ResourcePrefix:       prefix,
+		KeyFunc:              func(obj runtime.Object) (string, error) { return storage.NamespaceKeyFunc(prefix, obj) },
+		NewListFunc:          func() runtime.Object { return &api.PodList{} },
+		RelistOnExpiredWatch: true,
+	}
+	return storage.NewCacherFromConfig(config)
+}
+
 func makeTestPod(name string) *api.Pod {
 	return &api.Pod{
 		ObjectMeta: api.ObjectMeta{Namespace: "ns", Name: name},
@@ -200,10 +216,12 @@ func TestWatch(t *testing.T) {
 	verifyWatchEvent(t, watcher, watch.Added, podFoo)
 	verifyWatchEvent(t, watcher, watch.Modified, podFooPrime)
 
-	// Check whether we get too-old error.
+	// Check whether we get too-old error, typed so clients can relist.
 	_, err = cacher.Watch(context.TODO(), "pods/ns/foo", "1", storage.Everything)
 	if err == nil {
 		t.Errorf("Expected 'error too old' error")
+	} else if !errors.IsResourceExpired(err) {
+		t.Errorf("Expected 'expired' error, got: %v", err)
 	}
 
 	initialWatcher, err := cacher.Watch(context.TODO(), "pods/ns/foo", fooCreated.ResourceVersion, storage.Everything)
@@ -229,6 +247,73 @@ func TestWatch(t *testing.T) {
 	verifyWatchEvent(t, nowWatcher, watch.Modified, podFooBis)
 }
 
+func TestRelistOnExpiredWatch(t *testing.T) {
+	server, etcdStorage := newEtcdTestStorage(t, testapi.Default.Codec(), etcdtest.PathPrefix())
+	defer server.Terminate(t)
+	cacher := newTestRelistingCacher(etcdStorage)
+	defer cacher.Stop()
+
+	podFoo := makeTestPod("foo")
+	podBar := makeTestPod("bar")
+
+	podFooPrime := makeTestPod("foo")
+	podFooPrime.Spec.NodeName = "fakeNode"
+
+	// initialVersion is used to initate the watcher at the beginning of the world,
+	// which is not defined precisely in etcd.
+	initialVersion, err := cacher.LastSyncResourceVersion()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	startVersion := strconv.Itoa(int(initialVersion))
+
+	// Make sure the cacher has observed both pods before watching from an
+	// expired resourceVersion.
+	syncWatcher, err := cacher.WatchList(context.TODO(), "pods/ns", startVersion, storage.Everything)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	fooCreated := updatePod(t, etcdStorage, podFoo, nil)
+	_ = updatePod(t, etcdStorage, podBar, nil)
+	verifyWatchEvent(t, syncWatcher, watch.Added, podFoo)
+	verifyWatchEvent(t, syncWatcher, watch.Added, podBar)
+	syncWatcher.Stop()
+
+	// Version "1" has fallen out of the cache window; without relisting this
+	// fails with a 'too old' error (see TestWatch). With RelistOnExpiredWatch
+	// the watch succeeds and starts with the current state as Added events.
+	watcher, err := cacher.WatchList(context.TODO(), "pods/ns", "1", storage.Everything)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer watcher.Stop()
+
+	// The order of the initial Added events is not defined.
+	added := sets.String{}
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-watcher.ResultChan():
+			if e, a := watch.Added, event.Type; e != a {
+				t.Errorf("Expected: %s, got: %s", e, a)
+			}
+			metadata, err := meta.Accessor(event.Object)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			added.Insert(metadata.GetName())
+		case <-time.After(util.ForeverTestTimeout):
+			t.Fatalf("Timed out waiting for an event")
+		}
+	}
+	if !added.HasAll("foo", "bar") {
+		t.Errorf("Unexpected initial state: %v", added.List())
+	}
+
+	// Live events still flow after the relisted initial state.
+	_ = updatePod(t, etcdStorage, podFooPrime, fooCreated)
+	verifyWatchEvent(t, watcher, watch.Modified, podFooPrime)
+}
+
 func TestWatcherTimeout(t *testing.T) {
 	server, etcdStorage := newEtcdTestStorage(t, testapi.Default.Codec(), etcdtest.PathPrefix())
 	defer server.Terminate(t)
@@ -46,6 +46,10 @@ type EtcdConfig struct {
 	ServerList []string
 	Codec      runtime.Codec
 	Prefix     string
+	// Transformer, if set, is applied to serialized resource bodies on their
+	// way to and from etcd (e.g. to encrypt them at rest). Nil means no
+	// transformation.
+	Transformer storage.ValueTransformer
 }
 
 // implements storage.Config
@@ -72,12 +76,20 @@ func (c *EtcdConfig) NewStorage() (storage.Interface, error) {
 	if err != nil {
 		return nil, err
 	}
-	return NewEtcdStorage(etcdClient, c.Codec, c.Prefix), nil
+	return NewEtcdStorageWithTransformer(etcdClient, c.Codec, c.Prefix, c.Transformer), nil
 }
 
 // Creates a new storage interface from the client
 // TODO: deprecate in favor of storage.Config abstraction over time
 func NewEtcdStorage(client etcd.Client, codec runtime.Codec, prefix string) storage.Interface {
+	return NewEtcdStorageWithTransformer(client, codec, prefix, storage.IdentityTransformer)
+}
+
+// NewEtcdStorageWithTransformer is NewEtcdStorage with a transformer applied
+// to all values written to and read from etcd. Wrapping the codec covers
+// every serialization path, including watches.
+func NewEtcdStorageWithTransformer(client etcd.Client, codec runtime.Codec, prefix string, transformer storage.ValueTransformer) storage.Interface {
+	codec = storage.NewTransformingCodec(codec, transformer)
 	return &etcdHelper{
 		etcdclient: client,
 		client:     etcd.NewKeysAPI(client),
@@ -575,6 +587,40 @@ func (h *etcdHelper) GuaranteedUpdate(ctx context.Context, key string, ptrToType
 	}
 }
 
+// Implements storage.PrefixReporter.
+func (h *etcdHelper) Prefix() string {
+	return h.pathPrefix
+}
+
+// Implements storage.PrefixReporter. CountKeys walks the subtree under the
+// path prefix and counts leaf keys, for read-only migration diagnostics.
+func (h *etcdHelper) CountKeys(ctx context.Context) (int64, error) {
+	if ctx == nil {
+		glog.Errorf("Context is nil")
+	}
+	startTime := time.Now()
+	response, err := h.client.Get(ctx, h.pathPrefix, &etcd.GetOptions{Recursive: true})
+	metrics.RecordEtcdRequestLatency("get", "keyCount", startTime)
+	if err != nil {
+		if etcdutil.IsEtcdNotFound(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return countLeafNodes(response.Node), nil
+}
+
+func countLeafNodes(node *etcd.Node) int64 {
+	if !node.Dir {
+		return 1
+	}
+	count := int64(0)
+	for _, child := range node.Nodes {
+		count += countLeafNodes(child)
+	}
+	return count
+}
+
 func (h *etcdHelper) prefixEtcdKey(key string) string {
 	if strings.HasPrefix(key, h.pathPrefix) {
 		return key
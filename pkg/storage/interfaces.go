@@ -152,6 +152,19 @@ type Interface interface {
 	Codec() runtime.Codec
 }
 
+// PrefixReporter is an optional interface implemented by storage backends
+// that can report the key prefix their data lives under and how many keys
+// exist beneath it. It backs read-only diagnostics for storage prefix
+// migrations.
+type PrefixReporter interface {
+	// Prefix returns the key prefix all of this backend's data lives under.
+	Prefix() string
+
+	// CountKeys returns the number of leaf keys currently stored under the
+	// prefix.
+	CountKeys(ctx context.Context) (int64, error)
+}
+
 // Config interface allows storage tiers to generate the proper storage.interface
 // and reduce the dependencies to encapsulate storage.
 type Config interface {
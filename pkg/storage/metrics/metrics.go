@@ -0,0 +1,64 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	watchEventDeliveryLatencies = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "apiserver_watch_event_delivery_latencies",
+			Help: "Latency distribution in microseconds between receiving a storage event and delivering it to a watching client, per resource.",
+			// Use buckets ranging from 1 ms to ~1 second.
+			Buckets: prometheus.ExponentialBuckets(1000, 2.0, 10),
+		},
+		[]string{"resource"},
+	)
+	slowWatchTerminationCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "apiserver_watch_slow_termination_count",
+			Help: "Counter of watches terminated because the client could not keep up with the event stream, per resource.",
+		},
+		[]string{"resource"},
+	)
+)
+
+var registerMetrics sync.Once
+
+// Register all metrics.
+func Register() {
+	registerMetrics.Do(func() {
+		prometheus.MustRegister(watchEventDeliveryLatencies)
+		prometheus.MustRegister(slowWatchTerminationCounter)
+	})
+}
+
+// RecordWatchEventDelivery observes how long an event received at receivedAt
+// took to be handed to a watching client.
+func RecordWatchEventDelivery(resource string, receivedAt time.Time) {
+	watchEventDeliveryLatencies.WithLabelValues(resource).Observe(float64(time.Since(receivedAt) / time.Microsecond))
+}
+
+// RecordSlowWatchTermination counts a watch terminated for being too slow.
+func RecordSlowWatchTermination(resource string) {
+	slowWatchTerminationCounter.WithLabelValues(resource).Inc()
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/kubernetes/pkg/runtime"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+var readRetryCounter = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "storage_read_retries",
+		Help: "Number of idempotent storage reads retried after a transient error.",
+	},
+)
+
+var registerReadRetryMetric sync.Once
+
+// readRetryingStorage decorates an Interface so transient failures of
+// idempotent reads (Get, GetToList, List) are retried transparently instead
+// of being surfaced to the client, smoothing over short storage blips such
+// as etcd leader elections. Mutations are never retried, since replaying a
+// non-idempotent operation could duplicate a write.
+type readRetryingStorage struct {
+	Interface
+	attempts int
+	backoff  time.Duration
+}
+
+// NewReadRetryingStorage wraps the given storage so idempotent reads failing
+// with a transient error are retried up to 'attempts' times in total, waiting
+// 'backoff' between tries. All other operations pass through unchanged.
+func NewReadRetryingStorage(store Interface, attempts int, backoff time.Duration) Interface {
+	registerReadRetryMetric.Do(func() {
+		prometheus.MustRegister(readRetryCounter)
+	})
+	return &readRetryingStorage{Interface: store, attempts: attempts, backoff: backoff}
+}
+
+// Implements storage.Interface.
+func (s *readRetryingStorage) Get(ctx context.Context, key string, objPtr runtime.Object, ignoreNotFound bool) error {
+	return s.retry(ctx, func() error {
+		return s.Interface.Get(ctx, key, objPtr, ignoreNotFound)
+	})
+}
+
+// Implements storage.Interface.
+func (s *readRetryingStorage) GetToList(ctx context.Context, key string, filter FilterFunc, listObj runtime.Object) error {
+	return s.retry(ctx, func() error {
+		return s.Interface.GetToList(ctx, key, filter, listObj)
+	})
+}
+
+// Implements storage.Interface.
+func (s *readRetryingStorage) List(ctx context.Context, key string, resourceVersion string, filter FilterFunc, listObj runtime.Object) error {
+	return s.retry(ctx, func() error {
+		return s.Interface.List(ctx, key, resourceVersion, filter, listObj)
+	})
+}
+
+// retry runs read, repeating it on transient errors until it succeeds, fails
+// with an error that retrying cannot help (including not found), the attempt
+// budget is spent, or the request context expires.
+func (s *readRetryingStorage) retry(ctx context.Context, read func() error) error {
+	var err error
+	for attempt := 0; attempt < s.attempts; attempt++ {
+		if attempt > 0 {
+			readRetryCounter.Inc()
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(s.backoff):
+			}
+		}
+		err = read()
+		if err == nil || !IsUnreachable(err) {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/kubernetes/pkg/runtime"
+
+	etcd "github.com/coreos/etcd/client"
+	"golang.org/x/net/context"
+)
+
+// flakyReadStorage fails Get with the configured error until the failure
+// budget is exhausted. Only Get is implemented; the embedded nil Interface
+// makes any other call panic.
+type flakyReadStorage struct {
+	Interface
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakyReadStorage) Get(ctx context.Context, key string, objPtr runtime.Object, ignoreNotFound bool) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.err
+	}
+	return nil
+}
+
+func TestReadRetryingStorageGet(t *testing.T) {
+	table := []struct {
+		failures  int
+		err       error
+		wantErr   bool
+		wantCalls int
+	}{
+		// Transient errors are retried until they clear.
+		{failures: 2, err: etcd.ErrClusterUnavailable, wantErr: false, wantCalls: 3},
+		// A persistent transient error exhausts the attempt budget.
+		{failures: 5, err: etcd.ErrClusterUnavailable, wantErr: true, wantCalls: 3},
+		// Non-transient errors are surfaced immediately.
+		{failures: 5, err: errors.New("bad request"), wantErr: true, wantCalls: 1},
+	}
+	for i, item := range table {
+		flaky := &flakyReadStorage{failures: item.failures, err: item.err}
+		store := NewReadRetryingStorage(flaky, 3, time.Millisecond)
+		err := store.Get(context.TODO(), "/some/key", nil, false)
+		if hadErr := err != nil; hadErr != item.wantErr {
+			t.Errorf("%d: unexpected error state: %v", i, err)
+		}
+		if flaky.calls != item.wantCalls {
+			t.Errorf("%d: expected %d calls, got %d", i, item.wantCalls, flaky.calls)
+		}
+	}
+}
+
+func TestReadRetryingStorageRespectsContext(t *testing.T) {
+	flaky := &flakyReadStorage{failures: 100, err: etcd.ErrClusterUnavailable}
+	store := NewReadRetryingStorage(flaky, 10, time.Minute)
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	start := time.Now()
+	if err := store.Get(ctx, "/some/key", nil, false); err == nil {
+		t.Errorf("expected an error after the context was canceled")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("retry loop did not honor context cancellation, took %v", elapsed)
+	}
+	if flaky.calls != 1 {
+		t.Errorf("expected a single call before the canceled context stopped retries, got %d", flaky.calls)
+	}
+}
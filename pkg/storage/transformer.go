@@ -0,0 +1,122 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"io"
+	"net/url"
+
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	"k8s.io/kubernetes/pkg/runtime"
+)
+
+// ValueTransformer transforms serialized resource bodies on their way to and
+// from the backing store, e.g. to encrypt them at rest. Implementations must
+// satisfy TransformFromStorage(TransformToStorage(data)) == data.
+type ValueTransformer interface {
+	// TransformToStorage is applied to the serialized object before it is
+	// written to the store.
+	TransformToStorage(data []byte) ([]byte, error)
+	// TransformFromStorage is applied to data read from the store before it
+	// is deserialized.
+	TransformFromStorage(data []byte) ([]byte, error)
+}
+
+// IdentityTransformer passes data through unchanged.
+var IdentityTransformer ValueTransformer = identityTransformer{}
+
+type identityTransformer struct{}
+
+func (identityTransformer) TransformToStorage(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+func (identityTransformer) TransformFromStorage(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+// NewTransformingCodec wraps codec so that encoded bytes pass through
+// transformer.TransformToStorage and bytes being decoded pass through
+// transformer.TransformFromStorage first. Storage implementations serialize
+// exclusively through their codec, so wrapping it covers every read and
+// write path, including watches.
+func NewTransformingCodec(codec runtime.Codec, transformer ValueTransformer) runtime.Codec {
+	if transformer == nil || transformer == IdentityTransformer {
+		return codec
+	}
+	return &transformingCodec{codec: codec, transformer: transformer}
+}
+
+type transformingCodec struct {
+	codec       runtime.Codec
+	transformer ValueTransformer
+}
+
+func (c *transformingCodec) Encode(obj runtime.Object) ([]byte, error) {
+	data, err := c.codec.Encode(obj)
+	if err != nil {
+		return nil, err
+	}
+	return c.transformer.TransformToStorage(data)
+}
+
+func (c *transformingCodec) EncodeToStream(obj runtime.Object, stream io.Writer) error {
+	data, err := c.Encode(obj)
+	if err != nil {
+		return err
+	}
+	_, err = stream.Write(data)
+	return err
+}
+
+func (c *transformingCodec) Decode(data []byte) (runtime.Object, error) {
+	data, err := c.transformer.TransformFromStorage(data)
+	if err != nil {
+		return nil, err
+	}
+	return c.codec.Decode(data)
+}
+
+func (c *transformingCodec) DecodeToVersion(data []byte, groupVersion unversioned.GroupVersion) (runtime.Object, error) {
+	data, err := c.transformer.TransformFromStorage(data)
+	if err != nil {
+		return nil, err
+	}
+	return c.codec.DecodeToVersion(data, groupVersion)
+}
+
+func (c *transformingCodec) DecodeInto(data []byte, obj runtime.Object) error {
+	data, err := c.transformer.TransformFromStorage(data)
+	if err != nil {
+		return err
+	}
+	return c.codec.DecodeInto(data, obj)
+}
+
+func (c *transformingCodec) DecodeIntoWithSpecifiedVersionKind(data []byte, obj runtime.Object, groupVersionKind unversioned.GroupVersionKind) error {
+	data, err := c.transformer.TransformFromStorage(data)
+	if err != nil {
+		return err
+	}
+	return c.codec.DecodeIntoWithSpecifiedVersionKind(data, obj, groupVersionKind)
+}
+
+// DecodeParametersInto delegates unchanged: URL parameters never reach the
+// store, so they are not transformed.
+func (c *transformingCodec) DecodeParametersInto(parameters url.Values, obj runtime.Object) error {
+	return c.codec.DecodeParametersInto(parameters, obj)
+}
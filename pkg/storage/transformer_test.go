@@ -0,0 +1,119 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"testing"
+
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	"k8s.io/kubernetes/pkg/runtime"
+)
+
+// prefixTransformer marks stored values with a prefix, standing in for an
+// encrypting transformer.
+type prefixTransformer struct {
+	prefix []byte
+}
+
+func (t *prefixTransformer) TransformToStorage(data []byte) ([]byte, error) {
+	return append(append([]byte{}, t.prefix...), data...), nil
+}
+
+func (t *prefixTransformer) TransformFromStorage(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, t.prefix) {
+		return nil, fmt.Errorf("value does not have the expected prefix")
+	}
+	return data[len(t.prefix):], nil
+}
+
+// recordingCodec is a stub runtime.Codec that reports what bytes it was
+// asked to decode and encodes every object to a fixed value.
+type recordingCodec struct {
+	encoded []byte
+	decoded []byte
+}
+
+func (c *recordingCodec) Encode(obj runtime.Object) ([]byte, error) { return c.encoded, nil }
+
+func (c *recordingCodec) EncodeToStream(obj runtime.Object, stream io.Writer) error {
+	_, err := stream.Write(c.encoded)
+	return err
+}
+
+func (c *recordingCodec) Decode(data []byte) (runtime.Object, error) {
+	c.decoded = data
+	return nil, nil
+}
+
+func (c *recordingCodec) DecodeToVersion(data []byte, groupVersion unversioned.GroupVersion) (runtime.Object, error) {
+	c.decoded = data
+	return nil, nil
+}
+
+func (c *recordingCodec) DecodeInto(data []byte, obj runtime.Object) error {
+	c.decoded = data
+	return nil
+}
+
+func (c *recordingCodec) DecodeIntoWithSpecifiedVersionKind(data []byte, obj runtime.Object, groupVersionKind unversioned.GroupVersionKind) error {
+	c.decoded = data
+	return nil
+}
+
+func (c *recordingCodec) DecodeParametersInto(parameters url.Values, obj runtime.Object) error {
+	return nil
+}
+
+func TestTransformingCodec(t *testing.T) {
+	inner := &recordingCodec{encoded: []byte("payload")}
+	codec := NewTransformingCodec(inner, &prefixTransformer{prefix: []byte("ENC:")})
+
+	data, err := codec.Encode(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "ENC:payload"; string(data) != expected {
+		t.Errorf("expected encoded value %q, got %q", expected, string(data))
+	}
+
+	if err := codec.DecodeInto(data, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "payload"; string(inner.decoded) != expected {
+		t.Errorf("expected decoded value %q, got %q", expected, string(inner.decoded))
+	}
+
+	// Values missing the transformation are rejected rather than passed to
+	// the codec.
+	if err := codec.DecodeInto([]byte("plaintext"), nil); err == nil {
+		t.Errorf("expected error decoding untransformed value")
+	}
+}
+
+func TestTransformingCodecIdentity(t *testing.T) {
+	inner := &recordingCodec{}
+	if codec := NewTransformingCodec(inner, IdentityTransformer); codec != runtime.Codec(inner) {
+		t.Errorf("expected the identity transformer to leave the codec unwrapped")
+	}
+	if codec := NewTransformingCodec(inner, nil); codec != runtime.Codec(inner) {
+		t.Errorf("expected a nil transformer to leave the codec unwrapped")
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"sort"
 	"strconv"
 	"sync"
+	"time"
 
 	"k8s.io/kubernetes/pkg/api/errors"
 	"k8s.io/kubernetes/pkg/api/meta"
@@ -37,6 +38,9 @@ type watchCacheEvent struct {
 	Type       watch.EventType
 	Object     runtime.Object
 	PrevObject runtime.Object
+	// ReceivedAt is when the event was received from the underlying storage,
+	// used to measure delivery latency to watching clients.
+	ReceivedAt time.Time
 }
 
 // watchCacheElement is a single "watch event" stored in a cache.
@@ -167,7 +171,7 @@ func (w *watchCache) processEvent(event watch.Event, resourceVersion uint64, upd
 	if exists {
 		prevObject = previous.(runtime.Object)
 	}
-	watchCacheEvent := watchCacheEvent{event.Type, event.Object, prevObject}
+	watchCacheEvent := watchCacheEvent{event.Type, event.Object, prevObject, time.Now()}
 	if w.onEvent != nil {
 		w.onEvent(watchCacheEvent)
 	}
@@ -275,7 +279,8 @@ func (w *watchCache) GetAllEventsSinceThreadUnsafe(resourceVersion uint64) ([]wa
 		return result, nil
 	}
 	if resourceVersion < oldest {
-		return nil, errors.NewGone(fmt.Sprintf("too old resource version: %d (%d)", resourceVersion, oldest))
+		// Reason Expired tells clients to relist and resume watching.
+		return nil, errors.NewResourceExpired(fmt.Sprintf("too old resource version: %d (%d)", resourceVersion, oldest))
 	}
 
 	// Binary search the smallest index at which resourceVersion is not smaller than